@@ -0,0 +1,61 @@
+package mach
+
+import (
+	"os"
+	"sync"
+)
+
+// CheckedEntry is an entry that Check found worth logging. It comes from a
+// pool and goes back to it on Write, so it must not be used afterwards.
+type CheckedEntry struct {
+	l       *Logger
+	level   Level
+	msg     string
+	sampled bool
+}
+
+// checkedEntryPool recycles CheckedEntry structs. gohotpool only pools byte
+// buffers, so it can't hold them; the buffer Write encodes into still comes
+// from the logger's gohotpool.Pool, so the write path is the same as Info's.
+var checkedEntryPool = sync.Pool{
+	New: func() any { return new(CheckedEntry) },
+}
+
+// Check returns a CheckedEntry if an entry at level would be logged, and nil
+// otherwise, so that expensive fields are only built when they are needed:
+//
+//	if ce := log.Check(DebugLevel, "cache state"); ce != nil {
+//		ce.Write(String("dump", cache.Dump()))
+//	}
+//
+// Sampling is applied here rather than in Write.
+func (l *Logger) Check(level Level, msg string) *CheckedEntry {
+	if !l.enabled(level) {
+		return nil
+	}
+	sampled := false
+	if l.sampler != nil && l.recorder == nil {
+		if !l.sampler.allow(level, msg) {
+			return nil
+		}
+		sampled = true
+	}
+	ce := checkedEntryPool.Get().(*CheckedEntry)
+	ce.l, ce.level, ce.msg, ce.sampled = l, level, msg, sampled
+	return ce
+}
+
+// Write logs the entry with fields and releases ce. It is safe to call on a
+// nil CheckedEntry. Entries at FatalLevel exit the process like Fatal.
+func (ce *CheckedEntry) Write(fields ...Field) {
+	if ce == nil {
+		return
+	}
+	l, level := ce.l, ce.level
+	l.logSampled(level, ce.msg, fields, !ce.sampled)
+	*ce = CheckedEntry{}
+	checkedEntryPool.Put(ce)
+	if level >= FatalLevel {
+		os.Exit(1)
+	}
+}
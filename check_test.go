@@ -0,0 +1,55 @@
+package mach
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf})
+
+	if ce := log.Check(DebugLevel, "hidden"); ce != nil {
+		t.Fatal("Check returned an entry for a disabled level")
+	}
+	ce := log.Check(WarnLevel, "visible")
+	if ce == nil {
+		t.Fatal("Check returned nil for an enabled level")
+	}
+	ce.Write(Int("n", 1))
+	if !strings.HasSuffix(buf.String(), `"msg":"visible","n":1}`+"\n") {
+		t.Fatalf("got %q", buf.String())
+	}
+
+	var nilEntry *CheckedEntry
+	nilEntry.Write(Int("n", 2))
+}
+
+func TestCheckSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf, Sampling: &SamplingConfig{First: 2}})
+	passed := 0
+	for i := 0; i < 5; i++ {
+		if ce := log.Check(InfoLevel, "same"); ce != nil {
+			passed++
+			ce.Write()
+		}
+	}
+	if passed != 2 || strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("passed %d, wrote %q", passed, buf.String())
+	}
+}
+
+func TestCheckAllocs(t *testing.T) {
+	log := New(Config{Output: io.Discard})
+	allocs := testing.AllocsPerRun(100, func() {
+		if ce := log.Check(InfoLevel, "x"); ce != nil {
+			ce.Write(String("k", "v"), Int("n", 1))
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Check+Write allocated %v times", allocs)
+	}
+}
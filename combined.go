@@ -0,0 +1,102 @@
+package mach
+
+import "net"
+
+const combinedTimeFormat = "[02/Jan/2006:15:04:05 -0700]"
+
+type combinedEncoder struct{}
+
+var combinedEnc = &combinedEncoder{}
+
+// CombinedLogEncoder returns an encoder for HTTPMiddleware access entries in
+// the Apache/NGINX Combined Log Format:
+//
+//	192.0.2.1 - - [17/Feb/2026:22:30:00 +0000] "GET /users HTTP/1.1" 200 512 "-" "curl/8.5.0"
+//
+// It reads the remote_addr, method, path, proto, status, bytes, referer and
+// user_agent fields and ignores the rest, so it belongs on a sink of its own.
+func CombinedLogEncoder() EntryEncoder { return combinedEnc }
+
+// AppendContext keeps nothing; everything comes from the access entry.
+func (*combinedEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	return dst
+}
+
+func (*combinedEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	var remote, method, path, proto, referer, agent string
+	var status, size int64
+	for i := range fields {
+		f := &fields[i]
+		switch f.Key {
+		case "remote_addr":
+			remote = f.Str
+		case "method":
+			method = f.Str
+		case "path":
+			path = f.Str
+		case "proto":
+			proto = f.Str
+		case "referer":
+			referer = f.Str
+		case "user_agent":
+			agent = f.Str
+		case "status":
+			status = f.Ival
+		case "bytes":
+			size = f.Ival
+		}
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	dst = appendCombinedToken(dst, remote)
+	dst = append(dst, " - - "...)
+	dst = ent.Time.AppendFormat(dst, combinedTimeFormat)
+	dst = append(dst, " \""...)
+	dst = appendCombinedString(dst, method)
+	dst = append(dst, ' ')
+	dst = appendCombinedString(dst, path)
+	dst = append(dst, ' ')
+	dst = appendCombinedString(dst, proto)
+	dst = append(dst, "\" "...)
+	if status > 0 {
+		dst = appendInt64(dst, status)
+	} else {
+		dst = append(dst, '-')
+	}
+	dst = append(dst, ' ')
+	if size > 0 {
+		dst = appendInt64(dst, size)
+	} else {
+		dst = append(dst, '-')
+	}
+	dst = append(dst, " \""...)
+	dst = appendCombinedToken(dst, referer)
+	dst = append(dst, "\" \""...)
+	dst = appendCombinedToken(dst, agent)
+	return append(dst, '"', '\n')
+}
+
+func appendCombinedToken(dst []byte, s string) []byte {
+	if s == "" {
+		return append(dst, '-')
+	}
+	return appendCombinedString(dst, s)
+}
+
+// appendCombinedString escapes quotes, backslashes and non-printable bytes
+// the way Apache's mod_log_config does.
+func appendCombinedString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			dst = append(dst, '\\', 'x', hexDigit(c>>4), hexDigit(c&0x0f))
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,94 @@
+package mach
+
+import (
+	"math"
+	"time"
+)
+
+const consoleTimeFormat = "2006-01-02T15:04:05.000Z0700"
+
+type consoleEncoder struct{}
+
+var consoleEnc = &consoleEncoder{}
+
+// ConsoleEncoder returns a human-readable encoder:
+//
+//	2026-02-17T22:30:00.123Z	INFO	server started	addr=:8080 workers=4
+func ConsoleEncoder() EntryEncoder { return consoleEnc }
+
+func (*consoleEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	return appendConsoleContext(dst, fields)
+}
+
+func (*consoleEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	return appendConsoleEntry(dst, ent, context, fields)
+}
+
+func appendConsoleContext(dst []byte, fields []Field) []byte {
+	for i := range fields {
+		dst = append(dst, ' ')
+		dst = appendConsoleField(dst, fields[i])
+	}
+	return dst
+}
+
+func appendConsoleEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	dst = ent.Time.AppendFormat(dst, consoleTimeFormat)
+	dst = append(dst, '\t')
+	dst = append(dst, ent.Level.String()...)
+	dst = append(dst, '\t')
+	dst = appendConsoleString(dst, ent.Message, false)
+
+	mark := len(dst)
+	dst = append(dst, context...)
+	for i := range fields {
+		dst = append(dst, ' ')
+		dst = appendConsoleField(dst, fields[i])
+	}
+	if len(dst) > mark {
+		dst[mark] = '\t'
+	}
+	return append(dst, '\n')
+}
+
+func appendConsoleField(dst []byte, f Field) []byte {
+	dst = append(dst, f.Key...)
+	dst = append(dst, '=')
+	switch f.Type {
+	case StringType, ErrorType:
+		dst = appendConsoleString(dst, f.Str, true)
+	case IntType, Int64Type:
+		dst = appendInt64(dst, f.Ival)
+	case Float64Type:
+		dst = appendFloat64(dst, math.Float64frombits(uint64(f.Ival)))
+	case BoolType:
+		dst = appendBool(dst, f.Ival == 1)
+	case DurationType:
+		dst = appendDuration(dst, time.Duration(f.Ival))
+		dst = append(dst, 's')
+	case TimeType:
+		dst = time.Unix(0, f.Ival).AppendFormat(dst, time.RFC3339Nano)
+	case BytesType:
+		dst = appendConsoleString(dst, string(f.Bval), true)
+	}
+	return dst
+}
+
+// appendConsoleString writes s bare when it is unambiguous and JSON-quoted
+// otherwise. Messages are never quoted but still have control characters
+// escaped so one entry stays on one line.
+func appendConsoleString(dst []byte, s string, quoteIfNeeded bool) []byte {
+	if !quoteIfNeeded {
+		return appendEscapedString(dst, s)
+	}
+	if s == "" {
+		return append(dst, '"', '"')
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !safeSet[c] || c == ' ' || c == '=' {
+			return appendJSONString(dst, s)
+		}
+	}
+	return append(dst, s...)
+}
@@ -0,0 +1,155 @@
+package mach
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MYK12397/gohotpool"
+)
+
+type loggerKey struct{}
+
+type fieldsKey struct{}
+
+// ContextExtractor returns fields to add to entries logged with ctx, or nil.
+// It runs on every *Ctx call, so it should return a slice built ahead of time
+// (for example when the value was stored in the context) rather than
+// allocating.
+type ContextExtractor func(ctx context.Context) []Field
+
+var extractors atomic.Pointer[[]ContextExtractor]
+var extractorsMu sync.Mutex
+
+// RegisterContextExtractor adds fn to the extractors consulted by the *Ctx
+// logging methods. It is meant to be called during initialization.
+func RegisterContextExtractor(fn ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	var fns []ContextExtractor
+	if p := extractors.Load(); p != nil {
+		fns = append(fns, *p...)
+	}
+	fns = append(fns, fn)
+	extractors.Store(&fns)
+}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored by WithContext, or a logger that
+// discards everything if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return discardLogger()
+}
+
+var discardLogger = sync.OnceValue(func() *Logger {
+	return New(Config{
+		Output: io.Discard,
+		Level:  FatalLevel + 1,
+		PoolConfig: &gohotpool.Config{
+			PoolSize:          1,
+			ShardCount:        1,
+			DefaultBufferSize: 256,
+		},
+	})
+})
+
+// WithFields returns a copy of ctx carrying fields, in addition to any it
+// already carries. They are added to every entry logged through a *Ctx method
+// with that context.
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	prev, _ := ctx.Value(fieldsKey{}).([]Field)
+	all := make([]Field, 0, len(prev)+len(fields))
+	all = append(all, prev...)
+	for _, f := range fields {
+		all = append(all, cloneField(f))
+	}
+	return context.WithValue(ctx, fieldsKey{}, all)
+}
+
+func (l *Logger) TraceCtx(ctx context.Context, msg string, fields ...Field) {
+	if !l.enabled(TraceLevel) {
+		return
+	}
+	l.logCtx(ctx, TraceLevel, msg, fields)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	if !l.enabled(DebugLevel) {
+		return
+	}
+	l.logCtx(ctx, DebugLevel, msg, fields)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, msg string, fields ...Field) {
+	if !l.enabled(InfoLevel) {
+		return
+	}
+	l.logCtx(ctx, InfoLevel, msg, fields)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, msg string, fields ...Field) {
+	if !l.enabled(WarnLevel) {
+		return
+	}
+	l.logCtx(ctx, WarnLevel, msg, fields)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	if !l.enabled(ErrorLevel) {
+		return
+	}
+	l.logCtx(ctx, ErrorLevel, msg, fields)
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logCtx(ctx, FatalLevel, msg, fields)
+	os.Exit(1)
+}
+
+// LogCtx is Log with a context, like InfoCtx.
+func (l *Logger) LogCtx(ctx context.Context, level Level, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+	l.logCtx(ctx, level, msg, fields)
+}
+
+// logCtx prepends context fields, trace correlation fields and extractor
+// output to fields. The combined slice lives on the stack for typical field
+// counts, so nothing is allocated when the context contributes nothing or
+// only a few fields.
+func (l *Logger) logCtx(ctx context.Context, level Level, msg string, fields []Field) {
+	if ctx == nil {
+		l.log(level, msg, fields)
+		return
+	}
+
+	var arr [16]Field
+	all := arr[:0]
+	if fs, ok := ctx.Value(fieldsKey{}).([]Field); ok {
+		all = append(all, fs...)
+	}
+	all = appendTraceFields(ctx, all)
+	if p := extractors.Load(); p != nil {
+		for _, fn := range *p {
+			all = append(all, fn(ctx)...)
+		}
+	}
+	if len(all) == 0 {
+		l.log(level, msg, fields)
+		return
+	}
+	l.log(level, msg, append(all, fields...))
+}
@@ -0,0 +1,75 @@
+package mach
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf})
+	ctx := WithContext(context.Background(), log)
+	if FromContext(ctx) != log {
+		t.Fatal("FromContext returned a different logger")
+	}
+
+	d := FromContext(context.Background())
+	if d == nil || d.Enabled(FatalLevel) {
+		t.Fatal("fallback logger should discard everything")
+	}
+	d.Error("dropped")
+	if FromContext(context.TODO()) != d {
+		t.Fatal("fallback logger should be shared")
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	saved := extractors.Load()
+	t.Cleanup(func() { extractors.Store(saved) })
+	extractors.Store(nil)
+	RegisterContextExtractor(func(ctx context.Context) []Field {
+		if v, ok := ctx.Value(tenantKey{}).([]Field); ok {
+			return v
+		}
+		return nil
+	})
+	RegisterContextExtractor(func(ctx context.Context) []Field {
+		if ctx.Value(tenantKey{}) != nil {
+			return []Field{String("ext", "second")}
+		}
+		return nil
+	})
+
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf})
+	ctx := WithFields(context.Background(), String("request_id", "r1"))
+	base := ctx
+	ctx = WithFields(ctx, Int("attempt", 2))
+	ctx = ContextWithTrace(ctx, TraceContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Flags: 1})
+	ctx = context.WithValue(ctx, tenantKey{}, []Field{String("tenant", "acme")})
+
+	log.InfoCtx(ctx, "handled", Int("status", 200))
+	want := `"msg":"handled","request_id":"r1","attempt":2,` +
+		`"trace_id":"01000000000000000000000000000000","span_id":"0200000000000000","trace_flags":"01",` +
+		`"tenant":"acme","ext":"second","status":200}`
+	if got := buf.String(); !strings.HasSuffix(got, want+"\n") {
+		t.Fatalf("got  %s\nwant suffix %s", got, want)
+	}
+
+	// The parent context is unchanged.
+	buf.Reset()
+	log.LogCtx(base, WarnLevel, "parent")
+	if got := buf.String(); !strings.HasSuffix(got, `"msg":"parent","request_id":"r1"}`+"\n") {
+		t.Fatalf("got %s", got)
+	}
+
+	buf.Reset()
+	log.InfoCtx(context.Background(), "plain", String("k", "v"))
+	if got := buf.String(); !strings.HasSuffix(got, `"msg":"plain","k":"v"}`+"\n") {
+		t.Fatalf("got %s", got)
+	}
+}
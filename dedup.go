@@ -0,0 +1,138 @@
+package mach
+
+import (
+	"sync"
+	"time"
+)
+
+// deduper collapses runs of identical consecutive entries, syslogd style: the
+// first entry of a run is written, repeats within the window are counted, and
+// when the run ends the entry is written once more with a "repeated" count.
+type deduper struct {
+	window int64
+
+	mu       sync.Mutex
+	hash     uint64
+	start    int64 // monotime of the first entry of the run
+	repeated int
+	timer    *time.Timer
+
+	// The entry that started the run, kept in reused storage.
+	logger *Logger
+	level  Level
+	msg    string
+	fields []Field
+	bytes  []byte
+}
+
+func newDeduper(window time.Duration) *deduper {
+	return &deduper{window: int64(window)}
+}
+
+// check reports whether the entry repeats the current run and should be
+// dropped. A run that ends is flushed before check returns.
+func (d *deduper) check(l *Logger, level Level, msg string, fields []Field) bool {
+	h := hashEntry(level, msg, l.context, fields)
+	now := monotime()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.logger != nil && h == d.hash && now-d.start < d.window {
+		d.repeated++
+		if d.timer == nil {
+			d.timer = time.AfterFunc(time.Duration(d.window-(now-d.start)), d.expire)
+		}
+		return true
+	}
+
+	d.flushLocked()
+	d.hash = h
+	d.start = now
+	d.logger = l
+	d.level = level
+	d.msg = msg
+	d.fields = d.fields[:0]
+	d.bytes = d.bytes[:0]
+	for _, f := range fields {
+		if f.Bval != nil {
+			off := len(d.bytes)
+			d.bytes = append(d.bytes, f.Bval...)
+			f.Bval = d.bytes[off:len(d.bytes):len(d.bytes)]
+		}
+		d.fields = append(d.fields, f)
+	}
+	return false
+}
+
+func (d *deduper) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timer = nil
+	d.flushLocked()
+	d.logger = nil
+}
+
+func (d *deduper) flushLocked() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated == 0 {
+		return
+	}
+	n := d.repeated
+	d.repeated = 0
+	fields := append(d.fields, Int("repeated", n))
+	d.logger.write(d.level, d.msg, fields)
+	d.fields = fields[:len(fields)-1]
+}
+
+func hashEntry(level Level, msg string, context [][]byte, fields []Field) uint64 {
+	h := fnv64aInt(fnv64Offset, int64(level))
+	h = fnv64aString(h, msg)
+	for _, c := range context {
+		h = fnv64aBytes(h, c)
+	}
+	for i := range fields {
+		f := &fields[i]
+		h = fnv64aString(h, f.Key)
+		h = fnv64aInt(h, int64(f.Type))
+		h = fnv64aInt(h, f.Ival)
+		h = fnv64aString(h, f.Str)
+		h = fnv64aBytes(h, f.Bval)
+	}
+	return h
+}
+
+const (
+	fnv64Offset = 14695981039346656037
+	fnv64Prime  = 1099511628211
+)
+
+func fnv64aString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnv64Prime
+	}
+	// Separator so that ("ab","c") and ("a","bc") differ.
+	h ^= 0xff
+	return h * fnv64Prime
+}
+
+func fnv64aBytes(h uint64, b []byte) uint64 {
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnv64Prime
+	}
+	h ^= 0xff
+	return h * fnv64Prime
+}
+
+func fnv64aInt(h uint64, v int64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= uint64(byte(v >> (8 * i)))
+		h *= fnv64Prime
+	}
+	return h
+}
@@ -0,0 +1,71 @@
+package mach
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	var buf lockedBuffer
+	log := New(Config{Output: &buf, DedupWindow: time.Hour})
+
+	for i := 0; i < 4; i++ {
+		log.Warn("timeout", String("host", "db-1"), Bytes("raw", []byte("x")))
+	}
+	log.Warn("timeout", String("host", "db-2"))
+	log.Warn("timeout", String("host", "db-2"))
+	log.Error("timeout", String("host", "db-2")) // different level ends the run
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`"msg":"timeout","host":"db-1","raw":"x"}`,
+		`"msg":"timeout","host":"db-1","raw":"x","repeated":3}`,
+		`"msg":"timeout","host":"db-2"}`,
+		`"msg":"timeout","host":"db-2","repeated":1}`,
+		`"msg":"timeout","host":"db-2"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("line %d: %s, want suffix %s", i, lines[i], want[i])
+		}
+	}
+	if !strings.HasPrefix(lines[4], `{"level":"ERROR"`) {
+		t.Errorf("line 4: %s", lines[4])
+	}
+}
+
+func TestDedupWindowExpiry(t *testing.T) {
+	var buf lockedBuffer
+	log := New(Config{Output: &buf, DedupWindow: 20 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		log.Info("tick")
+	}
+	// Nothing ends the run, so the timer must flush it.
+	waitFor(t, &buf, `"msg":"tick","repeated":2}`)
+
+	log.Info("tick")
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Fatalf("entry after the window wasn't written: %q", buf.String())
+	}
+}
+
+func TestDedupContext(t *testing.T) {
+	var buf lockedBuffer
+	log := New(Config{Output: &buf, DedupWindow: time.Hour})
+	a := log.With(String("shard", "a"))
+	b := log.With(String("shard", "b"))
+
+	a.Info("x")
+	b.Info("x")
+	a.Info("x")
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 3 || strings.Contains(got, "repeated") {
+		t.Fatalf("children with different context were merged: %q", got)
+	}
+}
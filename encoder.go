@@ -2,6 +2,7 @@ package mach
 
 import (
 	"math"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -60,6 +61,108 @@ func appendField(dst []byte, f Field) []byte {
 	return dst
 }
 
+// Entry is the per-call part of a log line handed to an EntryEncoder.
+type Entry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+}
+
+// EntryEncoder serializes entries for a Sink. AppendContext is called once per
+// With call and its output is handed back to AppendEntry as context, so
+// implementations choose their own pre-encoded representation. AppendEntry
+// must terminate the entry (typically with '\n'). Encoders are compared with
+// == to share encoding work between sinks, so implementations should be
+// pointer types.
+type EntryEncoder interface {
+	AppendContext(dst []byte, fields []Field) []byte
+	AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte
+}
+
+type jsonEncoder struct{}
+
+var jsonEnc = &jsonEncoder{}
+
+// JSONEncoder returns the default encoder, one JSON object per line.
+func JSONEncoder() EntryEncoder { return jsonEnc }
+
+func (*jsonEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	return appendJSONContext(dst, fields)
+}
+
+func (*jsonEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	return appendJSONEntry(dst, ent, context, fields)
+}
+
+func appendJSONContext(dst []byte, fields []Field) []byte {
+	for i := range fields {
+		dst = append(dst, ',')
+		dst = appendField(dst, fields[i])
+	}
+	return dst
+}
+
+func appendJSONEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	dst = append(dst, `{"level":"`...)
+	dst = append(dst, ent.Level.String()...)
+	dst = append(dst, `","ts":`...)
+	dst = appendTime(dst, ent.Time)
+	dst = append(dst, `,"msg":`...)
+	dst = appendJSONString(dst, ent.Message)
+
+	if len(context) > 0 {
+		dst = append(dst, context...)
+	}
+
+	for i := range fields {
+		dst = append(dst, ',')
+		dst = appendField(dst, fields[i])
+	}
+
+	return append(dst, '}', '\n')
+}
+
+var fieldSlicePool = sync.Pool{
+	New: func() any {
+		s := make([]Field, 0, 16)
+		return &s
+	},
+}
+
+// encodeEntry dispatches to the built-in encoders directly. Anything else goes
+// through the interface with a pooled copy of fields, so that the caller's
+// variadic slice never escapes to the heap.
+func encodeEntry(dst []byte, enc EntryEncoder, ent Entry, context []byte, fields []Field) []byte {
+	switch enc.(type) {
+	case *jsonEncoder:
+		return appendJSONEntry(dst, ent, context, fields)
+	case *consoleEncoder:
+		return appendConsoleEntry(dst, ent, context, fields)
+	}
+	p := fieldSlicePool.Get().(*[]Field)
+	*p = append((*p)[:0], fields...)
+	dst = enc.AppendEntry(dst, ent, context, *p)
+	clear(*p)
+	fieldSlicePool.Put(p)
+	return dst
+}
+
+// encodeContext is encodeEntry's counterpart for With fields.
+func encodeContext(dst []byte, enc EntryEncoder, fields []Field) []byte {
+	switch enc.(type) {
+	case *jsonEncoder:
+		return appendJSONContext(dst, fields)
+	case *consoleEncoder:
+		return appendConsoleContext(dst, fields)
+	}
+	p := fieldSlicePool.Get().(*[]Field)
+	*p = append((*p)[:0], fields...)
+	dst = enc.AppendContext(dst, *p)
+	clear(*p)
+	fieldSlicePool.Put(p)
+	return dst
+}
+
 type Encoder struct {
 	buf *gohotpool.Buffer
 }
@@ -0,0 +1,132 @@
+package mach
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+type FallbackConfig struct {
+	// FailureThreshold is the number of consecutive failed writes that opens
+	// a writer's breaker. Default 3.
+	FailureThreshold int
+	// ProbeInterval is how long an open breaker diverts writes before a
+	// single write is let through to probe the writer again. Default 10s.
+	ProbeInterval time.Duration
+	// OnStateChange, if set, is called when writer i's breaker opens or
+	// closes.
+	OnStateChange func(i int, open bool)
+}
+
+// FallbackWriter writes each entry to the first writer in its chain whose
+// circuit breaker is closed, moving down the chain when a write fails so the
+// entry is not lost. The last writer is always attempted.
+type FallbackWriter struct {
+	cfg   FallbackConfig
+	links []breaker
+}
+
+const (
+	breakerClosed int32 = iota
+	breakerOpen
+	breakerProbing
+)
+
+type breaker struct {
+	w        io.Writer
+	state    atomic.Int32
+	failures atomic.Int32
+	retryAt  atomic.Int64 // unix nanos
+}
+
+func NewFallbackWriter(cfg FallbackConfig, writers ...io.Writer) *FallbackWriter {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 10 * time.Second
+	}
+	f := &FallbackWriter{cfg: cfg, links: make([]breaker, len(writers))}
+	for i, w := range writers {
+		f.links[i].w = w
+	}
+	return f
+}
+
+func (f *FallbackWriter) Write(p []byte) (int, error) {
+	if len(f.links) == 0 {
+		return 0, errors.New("mach: FallbackWriter has no writers")
+	}
+	var lastErr error
+	for i := range f.links {
+		b := &f.links[i]
+		last := i == len(f.links)-1
+		if !last && !b.allow(time.Now().UnixNano()) {
+			continue
+		}
+		n, err := b.w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			if b.success() {
+				f.notify(i, false)
+			}
+			return n, nil
+		}
+		if b.failure(time.Now().UnixNano(), f.cfg) {
+			f.notify(i, true)
+		}
+		lastErr = err
+	}
+	return 0, lastErr
+}
+
+// Open reports whether writer i's breaker is currently diverting writes.
+func (f *FallbackWriter) Open(i int) bool {
+	return f.links[i].state.Load() != breakerClosed
+}
+
+func (f *FallbackWriter) notify(i int, open bool) {
+	if f.cfg.OnStateChange != nil {
+		f.cfg.OnStateChange(i, open)
+	}
+}
+
+// allow reports whether a write may go to this writer. Once the probe
+// interval has passed, exactly one caller wins the right to probe.
+func (b *breaker) allow(now int64) bool {
+	switch b.state.Load() {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if now < b.retryAt.Load() {
+			return false
+		}
+		return b.state.CompareAndSwap(breakerOpen, breakerProbing)
+	}
+	return false
+}
+
+// success resets the breaker and reports whether it was open.
+func (b *breaker) success() bool {
+	if b.failures.Load() != 0 {
+		b.failures.Store(0)
+	}
+	if b.state.Load() == breakerClosed {
+		return false
+	}
+	b.state.Store(breakerClosed)
+	return true
+}
+
+// failure records a failed write and reports whether the breaker opened.
+func (b *breaker) failure(now int64, cfg FallbackConfig) bool {
+	wasProbing := b.state.Load() == breakerProbing
+	if !wasProbing && int(b.failures.Add(1)) < cfg.FailureThreshold {
+		return false
+	}
+	b.retryAt.Store(now + int64(cfg.ProbeInterval))
+	return b.state.Swap(breakerOpen) == breakerClosed
+}
@@ -0,0 +1,91 @@
+package mach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingWriter records writes and fails them while err is set.
+type countingWriter struct {
+	bytes.Buffer
+	calls int
+	err   error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFallbackWriterBreaker(t *testing.T) {
+	primary := &countingWriter{err: errors.New("down")}
+	backup := &countingWriter{}
+	var events []string
+	f := NewFallbackWriter(FallbackConfig{
+		FailureThreshold: 2,
+		ProbeInterval:    time.Hour,
+		OnStateChange: func(i int, open bool) {
+			events = append(events, fmt.Sprintf("%d:%v", i, open))
+		},
+	}, primary, backup)
+
+	// Each failed entry still reaches the backup; the second opens the breaker.
+	for i := 0; i < 2; i++ {
+		if _, err := f.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !f.Open(0) || primary.calls != 2 || backup.String() != "xx" {
+		t.Fatalf("open=%v primary=%d backup=%q", f.Open(0), primary.calls, backup.String())
+	}
+
+	// While open, writes skip the primary entirely.
+	_, _ = f.Write([]byte("y"))
+	if primary.calls != 2 || backup.String() != "xxy" {
+		t.Fatalf("primary=%d backup=%q", primary.calls, backup.String())
+	}
+
+	// A failed probe keeps the breaker open for another interval.
+	f.links[0].retryAt.Store(0)
+	_, _ = f.Write([]byte("z"))
+	if primary.calls != 3 || !f.Open(0) {
+		t.Fatalf("failed probe: primary=%d open=%v", primary.calls, f.Open(0))
+	}
+	_, _ = f.Write([]byte("z"))
+	if primary.calls != 3 {
+		t.Fatalf("wrote to primary %d times before the next probe", primary.calls)
+	}
+
+	// A successful probe closes it.
+	primary.err = nil
+	f.links[0].retryAt.Store(0)
+	_, _ = f.Write([]byte("a"))
+	_, _ = f.Write([]byte("b"))
+	if f.Open(0) || primary.String() != "ab" || backup.String() != "xxyzz" {
+		t.Fatalf("open=%v primary=%q backup=%q", f.Open(0), primary.String(), backup.String())
+	}
+
+	if got := fmt.Sprint(events); got != "[0:true 0:false]" {
+		t.Fatalf("state changes %s", got)
+	}
+}
+
+func TestFallbackWriterLastAlwaysTried(t *testing.T) {
+	a := &countingWriter{err: errors.New("a down")}
+	b := &countingWriter{err: errors.New("b down")}
+	f := NewFallbackWriter(FallbackConfig{FailureThreshold: 1, ProbeInterval: time.Hour}, a, b)
+	for i := 0; i < 3; i++ {
+		if _, err := f.Write([]byte("x")); err == nil || err.Error() != "b down" {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if a.calls != 1 || b.calls != 3 {
+		t.Fatalf("a=%d b=%d", a.calls, b.calls)
+	}
+}
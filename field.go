@@ -1,7 +1,9 @@
 package mach
 
 import (
+	"fmt"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -59,7 +61,7 @@ func Err(val error) Field {
 	if val == nil {
 		return Field{Key: "error", Type: StringType, Str: ""}
 	}
-	return Field{Key: "error", Type: ErrorType, Str: val.Error()}
+	return Field{Key: "error", Type: ErrorType, Str: errorString(val)}
 }
 
 func Time(key string, val time.Time) Field {
@@ -69,3 +71,68 @@ func Time(key string, val time.Time) Field {
 func Bytes(key string, val []byte) Field {
 	return Field{Key: key, Type: BytesType, Bval: val}
 }
+
+// Any picks a field type from val's dynamic type, without reflection for the
+// common cases. Other values, fmt.Stringers included, are formatted with
+// fmt.Sprint.
+func Any(key string, val any) Field {
+	switch v := val.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case int16:
+		return Int64(key, int64(v))
+	case int8:
+		return Int64(key, int64(v))
+	case uint:
+		return uintField(key, uint64(v))
+	case uint64:
+		return uintField(key, v)
+	case uint32:
+		return Int64(key, int64(v))
+	case uint16:
+		return Int64(key, int64(v))
+	case uint8:
+		return Int64(key, int64(v))
+	case float64:
+		return Float64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case []byte:
+		return Bytes(key, v)
+	case error:
+		return Field{Key: key, Type: ErrorType, Str: errorString(v)}
+	}
+	return String(key, fmt.Sprint(val))
+}
+
+// errorString returns err.Error(). If that panics, as it does for most
+// typed nil pointers, it falls back to fmt, which prints "<nil>" for those
+// and a PANIC note otherwise.
+func errorString(err error) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = fmt.Sprint(err)
+		}
+	}()
+	return err.Error()
+}
+
+// uintField keeps values above math.MaxInt64 exact by writing them as strings.
+func uintField(key string, v uint64) Field {
+	if v > math.MaxInt64 {
+		return String(key, strconv.FormatUint(v, 10))
+	}
+	return Int64(key, int64(v))
+}
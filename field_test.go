@@ -0,0 +1,26 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type ptrError struct{ msg string }
+
+func (e *ptrError) Error() string { return e.msg }
+
+func TestAnyTypedNil(t *testing.T) {
+	var buf bytes.Buffer
+	var err *ptrError
+	log := New(Config{Output: &buf})
+	log.Info("x", Any("err", err), Any("ok", &ptrError{"fine"}))
+	log.Info("y", Err(err))
+
+	got := buf.String()
+	for _, want := range []string{`"err":"<nil>","ok":"fine"}`, `"msg":"y","error":"<nil>"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package mach
+
+import (
+	"slices"
+	"strings"
+)
+
+// FieldFilter limits the fields a sink receives, both per call and from
+// With. A field passes if it matches the allow rules, when there are any,
+// and none of the deny rules. Keys match exactly; Prefixes match namespaces
+// such as "http." by key prefix. With both AllowKeys and AllowPrefixes set, a
+// field may match either.
+//
+// Sinks with the same encoder and equal filters share one encoding pass.
+type FieldFilter struct {
+	AllowKeys     []string
+	DenyKeys      []string
+	AllowPrefixes []string
+	DenyPrefixes  []string
+	AllowTypes    []FieldType
+	DenyTypes     []FieldType
+}
+
+// fieldFilter is the compiled form of a FieldFilter.
+type fieldFilter struct {
+	allowKeys     map[string]struct{}
+	denyKeys      map[string]struct{}
+	allowPrefixes []string
+	denyPrefixes  []string
+	allowTypes    uint32 // bit per FieldType; zero allows all
+	denyTypes     uint32
+}
+
+func newFieldFilter(f *FieldFilter) *fieldFilter {
+	if f == nil {
+		return nil
+	}
+	ff := &fieldFilter{
+		allowKeys:     keySet(f.AllowKeys),
+		denyKeys:      keySet(f.DenyKeys),
+		allowPrefixes: slices.Clone(f.AllowPrefixes),
+		denyPrefixes:  slices.Clone(f.DenyPrefixes),
+		allowTypes:    typeMask(f.AllowTypes),
+		denyTypes:     typeMask(f.DenyTypes),
+	}
+	slices.Sort(ff.allowPrefixes)
+	slices.Sort(ff.denyPrefixes)
+	return ff
+}
+
+func keySet(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	m := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		m[k] = struct{}{}
+	}
+	return m
+}
+
+func typeMask(types []FieldType) uint32 {
+	var m uint32
+	for _, t := range types {
+		m |= 1 << t
+	}
+	return m
+}
+
+// allow reports whether f passes the filter.
+func (ff *fieldFilter) allow(f *Field) bool {
+	if ff.allowTypes != 0 && ff.allowTypes&(1<<f.Type) == 0 {
+		return false
+	}
+	if ff.denyTypes&(1<<f.Type) != 0 {
+		return false
+	}
+	if _, ok := ff.denyKeys[f.Key]; ok {
+		return false
+	}
+	if hasAnyPrefix(f.Key, ff.denyPrefixes) {
+		return false
+	}
+	if len(ff.allowKeys) == 0 && len(ff.allowPrefixes) == 0 {
+		return true // empty allow lists are no rule, not "allow nothing"
+	}
+	if _, ok := ff.allowKeys[f.Key]; ok {
+		return true
+	}
+	return hasAnyPrefix(f.Key, ff.allowPrefixes)
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply appends the fields that pass the filter to dst.
+func (ff *fieldFilter) apply(dst []Field, fields []Field) []Field {
+	for i := range fields {
+		if ff.allow(&fields[i]) {
+			dst = append(dst, fields[i])
+		}
+	}
+	return dst
+}
+
+// equal reports whether ff and o pass the same fields, so sinks using them
+// can share an encoding.
+func (ff *fieldFilter) equal(o *fieldFilter) bool {
+	if ff == nil || o == nil {
+		return ff == o
+	}
+	return ff.allowTypes == o.allowTypes && ff.denyTypes == o.denyTypes &&
+		sameKeys(ff.allowKeys, o.allowKeys) && sameKeys(ff.denyKeys, o.denyKeys) &&
+		slices.Equal(ff.allowPrefixes, o.allowPrefixes) &&
+		slices.Equal(ff.denyPrefixes, o.denyPrefixes)
+}
+
+func sameKeys(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package mach
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSinkFilter(t *testing.T) {
+	var audit, debug, audit2 bytes.Buffer
+	log := New(Config{Sinks: []Sink{
+		{Output: &audit, Filter: &FieldFilter{
+			AllowKeys:     []string{"user", "action"},
+			AllowPrefixes: []string{"audit."},
+			DenyTypes:     []FieldType{BytesType},
+		}},
+		{Output: &debug},
+		{Output: &audit2, Filter: &FieldFilter{
+			AllowPrefixes: []string{"audit."},
+			AllowKeys:     []string{"action", "user"},
+			DenyTypes:     []FieldType{BytesType},
+		}},
+	}}).With(String("user", "bob"), String("svc", "api"))
+
+	log.Info("deleted",
+		String("action", "delete"),
+		String("audit.target", "doc-1"),
+		Bytes("audit.raw", []byte("x")),
+		Duration("took", time.Millisecond),
+	)
+
+	if got := audit.String(); !strings.HasSuffix(got, `"msg":"deleted","user":"bob","action":"delete","audit.target":"doc-1"}`+"\n") {
+		t.Fatalf("audit: %q", got)
+	}
+	if audit2.String() != audit.String() {
+		t.Fatalf("audit2: %q", audit2.String())
+	}
+	for _, want := range []string{`"svc":"api"`, `"audit.raw"`, `"took"`} {
+		if !strings.Contains(debug.String(), want) {
+			t.Errorf("debug missing %s: %q", want, debug.String())
+		}
+	}
+	if n := len(log.groups); n != 2 {
+		t.Fatalf("equal filters should share a group, got %d groups", n)
+	}
+}
+
+func TestSinkFilterDeny(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Sinks: []Sink{{Output: &buf, Filter: &FieldFilter{
+		DenyKeys:     []string{"password"},
+		DenyPrefixes: []string{"debug."},
+		AllowTypes:   []FieldType{StringType, IntType},
+	}}}})
+	log.Info("x", String("password", "p"), Int("debug.n", 1), Bool("ok", true), Int("n", 2), String("s", "v"))
+	if got := buf.String(); !strings.HasSuffix(got, `"msg":"x","n":2,"s":"v"}`+"\n") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSinkFilterEmptyAllow(t *testing.T) {
+	for _, f := range []*FieldFilter{
+		{AllowKeys: []string{}},
+		{AllowPrefixes: []string{}},
+		{AllowKeys: []string{}, AllowPrefixes: []string{}, DenyKeys: []string{"b"}},
+	} {
+		var buf bytes.Buffer
+		log := New(Config{Sinks: []Sink{{Output: &buf, Filter: f}}})
+		log.Info("x", Int("a", 1), Int("b", 2))
+		want := `"a":1,"b":2}`
+		if f.DenyKeys != nil {
+			want = `"a":1}`
+		}
+		if got := buf.String(); !strings.HasSuffix(got, `"msg":"x",`+want+"\n") {
+			t.Errorf("filter %+v: got %q", *f, got)
+		}
+	}
+}
+
+func TestSinkFilterAllocs(t *testing.T) {
+	log := New(Config{Sinks: []Sink{
+		{Output: io.Discard, Filter: &FieldFilter{AllowKeys: []string{"k"}}},
+		{Output: io.Discard},
+	}})
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("x", String("k", "v"), Int("n", 1))
+	})
+	if allocs != 0 {
+		t.Fatalf("allocated %v times", allocs)
+	}
+}
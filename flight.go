@@ -0,0 +1,122 @@
+package mach
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MYK12397/gohotpool"
+)
+
+// flightRecorder keeps the most recent entries that were below the logger's
+// level, encoded into pooled buffers, and writes them out ahead of the next
+// entry at or above the trigger level.
+type flightRecorder struct {
+	capture Level
+	trigger Level
+
+	mu    sync.Mutex
+	slots [][]*gohotpool.Buffer // one buffer per sink group
+	next  int
+	full  bool
+}
+
+// FlightRecorder returns a child logger that records up to size entries at
+// or above capture that its level would otherwise discard. When an entry at
+// or above trigger is logged, the recorded entries are written first, to
+// every sink that accepts the triggering entry, and the buffer is cleared.
+// Create one per request to get the debug trail of just that request, and
+// call DiscardFlightRecord when the request is done.
+func (l *Logger) FlightRecorder(size int, capture, trigger Level) *Logger {
+	if size <= 0 {
+		size = 64
+	}
+	child := *l
+	child.recorder = &flightRecorder{
+		capture: capture,
+		trigger: trigger,
+		slots:   make([][]*gohotpool.Buffer, size),
+	}
+	return &child
+}
+
+func (r *flightRecorder) record(l *Logger, level Level, msg string, fields []Field) {
+	if l.redactor != nil {
+		p := l.redactor.redactPooled(fields)
+		defer releaseFields(p)
+		fields = *p
+	}
+	ent := Entry{Level: level, Time: time.Now(), Message: msg}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bufs := r.slots[r.next]
+	if bufs == nil {
+		bufs = make([]*gohotpool.Buffer, len(l.groups))
+		r.slots[r.next] = bufs
+	}
+	for i := range l.groups {
+		if bufs[i] == nil {
+			bufs[i] = l.pool.Get()
+		}
+		bufs[i].B = l.groups[i].encode(bufs[i].B[:0], ent, l.context[i], fields)
+	}
+	r.next++
+	if r.next == len(r.slots) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// dump writes the recorded entries oldest first and returns their buffers
+// to the pool.
+func (r *flightRecorder) dump(l *Logger, trigger Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.full {
+		r.flushSlots(l, trigger, r.slots[r.next:])
+	}
+	r.flushSlots(l, trigger, r.slots[:r.next])
+	r.next = 0
+	r.full = false
+}
+
+// DiscardFlightRecord drops the entries held by l's flight recorder without
+// writing them and returns their buffers to the pool. A per-request recorder
+// that never sees a trigger would otherwise keep them until it is collected.
+func (l *Logger) DiscardFlightRecord() {
+	if l.recorder == nil {
+		return
+	}
+	r := l.recorder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, bufs := range r.slots {
+		for i, buf := range bufs {
+			if buf == nil {
+				continue
+			}
+			buf.Reset()
+			l.pool.Put(buf)
+			bufs[i] = nil
+		}
+	}
+	r.next = 0
+	r.full = false
+}
+
+func (r *flightRecorder) flushSlots(l *Logger, trigger Level, slots [][]*gohotpool.Buffer) {
+	for _, bufs := range slots {
+		for i, buf := range bufs {
+			if buf == nil {
+				continue
+			}
+			l.output(&l.groups[i], trigger, buf.B)
+			buf.Reset()
+			l.pool.Put(buf)
+			bufs[i] = nil
+		}
+	}
+}
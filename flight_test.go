@@ -0,0 +1,96 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func lines(b *bytes.Buffer) []string {
+	s := strings.TrimSpace(b.String())
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func TestFlightRecorderWrap(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf}).FlightRecorder(3, DebugLevel, ErrorLevel)
+
+	for i := 0; i < 5; i++ {
+		log.Debug("step", Int("i", i))
+	}
+	log.Trace("below capture")
+	log.Info("written")
+	if got := lines(&buf); len(got) != 1 {
+		t.Fatalf("recorded entries leaked early: %q", got)
+	}
+
+	log.Error("failed")
+	got := lines(&buf)
+	want := []string{`"msg":"written"}`, `"i":2}`, `"i":3}`, `"i":4}`, `"msg":"failed"}`}
+	if len(got) != len(want) {
+		t.Fatalf("got %q", got)
+	}
+	for i := range want {
+		if !strings.HasSuffix(got[i], want[i]) {
+			t.Errorf("line %d: %s, want suffix %s", i, got[i], want[i])
+		}
+	}
+	if !strings.HasPrefix(got[1], `{"level":"DEBUG"`) {
+		t.Errorf("recorded entry keeps its level: %s", got[1])
+	}
+
+	// The dump cleared the ring.
+	buf.Reset()
+	log.Error("again")
+	if got := lines(&buf); len(got) != 1 {
+		t.Fatalf("second trigger: %q", got)
+	}
+}
+
+func TestFlightRecorderSinks(t *testing.T) {
+	var debug, errs bytes.Buffer
+	log := New(Config{Sinks: []Sink{
+		{Output: &debug, Level: DebugLevel},
+		{Output: &errs, Level: ErrorLevel},
+	}}).FlightRecorder(8, DebugLevel, WarnLevel)
+
+	log.Debug("d1")
+	log.Warn("w") // below the error sink, so it gets neither
+	if len(lines(&debug)) != 2 || len(lines(&errs)) != 0 {
+		t.Fatalf("debug %q, errs %q", debug.String(), errs.String())
+	}
+
+	log.Debug("d2")
+	log.Error("e")
+	if got := lines(&errs); len(got) != 2 || !strings.Contains(got[0], `"msg":"d2"`) {
+		t.Fatalf("errs %q", got)
+	}
+	if got := lines(&debug); len(got) != 4 {
+		t.Fatalf("debug %q", got)
+	}
+}
+
+func TestFlightRecorderDiscard(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf}).FlightRecorder(4, DebugLevel, ErrorLevel)
+	for i := 0; i < 6; i++ {
+		log.Debug("step")
+	}
+	log.DiscardFlightRecord()
+	for _, bufs := range log.recorder.slots {
+		for _, b := range bufs {
+			if b != nil {
+				t.Fatal("buffer still held after discard")
+			}
+		}
+	}
+
+	log.Error("failed")
+	if got := lines(&buf); len(got) != 1 {
+		t.Fatalf("discarded entries were written: %q", got)
+	}
+	New(Config{Output: &buf}).DiscardFlightRecord() // no recorder
+}
@@ -5,6 +5,7 @@ go 1.23.1
 require (
 	github.com/MYK12397/gohotpool v1.0.0
 	go.uber.org/zap v1.27.1
+	golang.org/x/sys v0.35.0
 )
 
 require go.uber.org/multierr v1.10.0 // indirect
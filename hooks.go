@@ -0,0 +1,96 @@
+package mach
+
+import (
+	"sync"
+	"time"
+)
+
+// HookEntry is the view of an entry that hooks receive. Fields are the
+// per-call fields and Context the fields added with With once the logger had
+// hooks or a redaction policy; earlier ones are only kept encoded. It is
+// reused after the hooks return, so hooks must copy anything they keep.
+type HookEntry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+	Fields  []Field
+	Context []Field
+}
+
+// PreHook runs before an entry is encoded. It may change the level, message
+// and fields, including appending to Fields, and returns false to drop the
+// entry. A redaction policy applies to Fields after the pre-hooks have run,
+// so they see the values as logged and post-hooks see them redacted.
+type PreHook func(e *HookEntry) bool
+
+// PostHook runs after an entry has been written to the sinks, e.g. to count
+// it or forward errors to an alerting channel.
+type PostHook func(e *HookEntry)
+
+type hooks struct {
+	pre  []PreHook
+	post []PostHook
+}
+
+var hookEntryPool = sync.Pool{
+	New: func() any {
+		return &HookEntry{Fields: make([]Field, 0, 16)}
+	},
+}
+
+// WithPreHook returns a child logger that runs fn, after any hooks it
+// already has, on each entry that passed level, sampling and rate limiting.
+// Loggers without hooks pay nothing for the feature.
+func (l *Logger) WithPreHook(fn PreHook) *Logger {
+	child := *l
+	child.hooks = &hooks{}
+	if l.hooks != nil {
+		*child.hooks = *l.hooks
+	}
+	child.hooks.pre = append(child.hooks.pre[:len(child.hooks.pre):len(child.hooks.pre)], fn)
+	return &child
+}
+
+// WithPostHook returns a child logger that runs fn, after any hooks it
+// already has, once each entry has been written.
+func (l *Logger) WithPostHook(fn PostHook) *Logger {
+	child := *l
+	child.hooks = &hooks{}
+	if l.hooks != nil {
+		*child.hooks = *l.hooks
+	}
+	child.hooks.post = append(child.hooks.post[:len(child.hooks.post):len(child.hooks.post)], fn)
+	return &child
+}
+
+// run hands hooks a pooled copy of fields, so that the caller's variadic
+// slice doesn't escape through the function values.
+func (h *hooks) run(l *Logger, ent Entry, fields []Field) {
+	e := hookEntryPool.Get().(*HookEntry)
+	e.Level, e.Time, e.Message = ent.Level, ent.Time, ent.Message
+	e.Fields = append(e.Fields[:0], fields...)
+	e.Context = l.fields
+
+	ok := true
+	for _, fn := range h.pre {
+		if ok = fn(e); !ok {
+			break
+		}
+	}
+	if ok {
+		if l.redactor != nil {
+			// In place: redact never writes past the field it is reading.
+			e.Fields = l.redactor.redact(e.Fields[:0], e.Fields)
+		}
+		l.writeEntry(Entry{Level: e.Level, Time: e.Time, Message: e.Message}, e.Fields)
+		for _, fn := range h.post {
+			fn(e)
+		}
+	}
+
+	clear(e.Fields)
+	e.Fields = e.Fields[:0]
+	e.Context = nil
+	e.Message = ""
+	hookEntryPool.Put(e)
+}
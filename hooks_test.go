@@ -0,0 +1,82 @@
+package mach
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var buf bytes.Buffer
+	var errs []string
+	log := New(Config{Output: &buf}).
+		WithPreHook(func(e *HookEntry) bool {
+			if e.Message == "noisy" {
+				return false
+			}
+			if len(e.Context) != 1 || e.Context[0].Key != "svc" {
+				t.Errorf("context %v", e.Context)
+			}
+			e.Fields = append(e.Fields, String("region", "eu"))
+			if e.Message == "escalate" {
+				e.Level = ErrorLevel
+			}
+			return true
+		}).
+		With(String("svc", "api")).
+		WithPostHook(func(e *HookEntry) {
+			if e.Level >= ErrorLevel {
+				errs = append(errs, e.Message)
+			}
+		})
+
+	log.Info("noisy")
+	log.Info("hello", Int("n", 1))
+	log.Warn("escalate")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `"msg":"hello","svc":"api","n":1,"region":"eu"}`) {
+		t.Fatalf("line 0: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `{"level":"ERROR"`) {
+		t.Fatalf("line 1: %q", lines[1])
+	}
+	if len(errs) != 1 || errs[0] != "escalate" {
+		t.Fatalf("post hook saw %v", errs)
+	}
+}
+
+func TestWithWithoutHooks(t *testing.T) {
+	log := New(Config{Output: io.Discard})
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = log.With(String("a", "1"), String("b", "2"), String("c", "3"))
+	})
+	if allocs != 2 {
+		t.Fatalf("With allocated %v times, want 2", allocs)
+	}
+
+	// With fields that predate the hooks were only kept encoded.
+	var ctx []Field
+	log.With(String("early", "1")).
+		WithPreHook(func(e *HookEntry) bool { ctx = e.Context; return true }).
+		With(String("late", "2")).
+		Info("x")
+	if len(ctx) != 1 || ctx[0].Key != "late" {
+		t.Fatalf("context %v", ctx)
+	}
+}
+
+func TestHooksAllocs(t *testing.T) {
+	n := 0
+	log := New(Config{Output: io.Discard}).WithPostHook(func(e *HookEntry) { n++ })
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("x", String("k", "v"), Int("n", 1))
+	})
+	if allocs != 0 || n == 0 {
+		t.Fatalf("allocs %v, calls %d", allocs, n)
+	}
+}
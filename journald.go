@@ -0,0 +1,209 @@
+//go:build linux
+
+package mach
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const defaultJournalSocket = "/run/systemd/journal/socket"
+
+type JournalConfig struct {
+	SocketPath string // defaults to /run/systemd/journal/socket
+	Identifier string // SYSLOG_IDENTIFIER, defaults to the program name
+}
+
+// JournalWriter sends each Write as one entry over the journald native
+// protocol. Entries too large for a datagram are passed as a sealed memfd.
+// Pair it with the encoder from Encoder, or use Sink.
+type JournalWriter struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+	enc  *journalEncoder
+}
+
+func NewJournalWriter(cfg JournalConfig) (*JournalWriter, error) {
+	if cfg.SocketPath == "" {
+		cfg.SocketPath = defaultJournalSocket
+	}
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournalWriter{
+		conn: conn,
+		addr: &net.UnixAddr{Name: cfg.SocketPath, Net: "unixgram"},
+		enc:  &journalEncoder{identifier: cfg.Identifier},
+	}, nil
+}
+
+// Encoder returns the encoder producing journald native entries.
+func (w *JournalWriter) Encoder() EntryEncoder { return w.enc }
+
+// Sink returns a Sink writing to w with its encoder.
+func (w *JournalWriter) Sink(level Level) Sink {
+	return Sink{Output: w, Level: level, Encoder: w.enc}
+}
+
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	_, err := w.conn.WriteToUnix(p, w.addr)
+	if err == nil {
+		return len(p), nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return 0, err
+	}
+	if err := w.writeMemfd(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *JournalWriter) writeMemfd(p []byte) error {
+	fd, err := unix.MemfdCreate("mach-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	f := os.NewFile(uintptr(fd), "mach-journal")
+	defer f.Close()
+
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr)
+	return err
+}
+
+func (w *JournalWriter) Close() error {
+	return w.conn.Close()
+}
+
+type journalEncoder struct {
+	identifier string
+}
+
+// journalPriority maps a Level onto the syslog priority journald expects.
+func journalPriority(l Level) byte {
+	return byte('0' + syslogSeverity(l))
+}
+
+func (e *journalEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	for i := range fields {
+		dst = appendJournalField(dst, fields[i])
+	}
+	return dst
+}
+
+func (e *journalEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	dst = append(dst, "PRIORITY="...)
+	dst = append(dst, journalPriority(ent.Level), '\n')
+	dst = appendJournalValue(append(dst, "SYSLOG_IDENTIFIER"...), e.identifier)
+	dst = appendJournalValue(append(dst, "MESSAGE"...), ent.Message)
+	dst = append(dst, context...)
+	for i := range fields {
+		dst = appendJournalField(dst, fields[i])
+	}
+	return dst
+}
+
+func appendJournalField(dst []byte, f Field) []byte {
+	switch f.Type {
+	case StringType, ErrorType:
+		return appendJournalVar(dst, f.Key, f.Str)
+	case BytesType:
+		return appendJournalVar(dst, f.Key, string(f.Bval))
+	}
+
+	dst = appendJournalKey(dst, f.Key)
+	dst = append(dst, '=')
+	switch f.Type {
+	case IntType, Int64Type:
+		dst = appendInt64(dst, f.Ival)
+	case Float64Type:
+		dst = appendFloat64(dst, math.Float64frombits(uint64(f.Ival)))
+	case BoolType:
+		dst = appendBool(dst, f.Ival == 1)
+	case DurationType:
+		dst = appendDuration(dst, time.Duration(f.Ival))
+	case TimeType:
+		dst = time.Unix(0, f.Ival).AppendFormat(dst, time.RFC3339Nano)
+	}
+	return append(dst, '\n')
+}
+
+func appendJournalVar(dst []byte, key, val string) []byte {
+	return appendJournalValue(appendJournalKey(dst, key), val)
+}
+
+// appendJournalValue completes a variable whose name is already in dst, as
+// =value, or in the length-prefixed binary form when the value contains a
+// newline.
+func appendJournalValue(dst []byte, val string) []byte {
+	if strings.IndexByte(val, '\n') < 0 {
+		dst = append(dst, '=')
+		dst = append(dst, val...)
+		return append(dst, '\n')
+	}
+	dst = append(dst, '\n')
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(val)))
+	dst = append(dst, val...)
+	return append(dst, '\n')
+}
+
+// appendJournalKey upper-cases key and replaces anything outside [A-Z0-9_].
+// Leading underscores are dropped because journald reserves them for trusted
+// fields, and a leading digit is prefixed. So are keys that would become one
+// of the fields the encoder writes itself, so they don't add a second value
+// to it.
+func appendJournalKey(dst []byte, key string) []byte {
+	start := len(dst)
+	dst = appendJournalName(dst, key)
+	switch string(dst[start:]) {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		dst = append(dst, 0, 0)
+		copy(dst[start+2:], dst[start:])
+		dst[start], dst[start+1] = 'F', '_'
+	}
+	return dst
+}
+
+func appendJournalName(dst []byte, key string) []byte {
+	for len(key) > 0 && key[0] == '_' {
+		key = key[1:]
+	}
+	if key == "" {
+		return append(dst, "FIELD"...)
+	}
+	if key[0] >= '0' && key[0] <= '9' {
+		dst = append(dst, 'F', '_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		default:
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
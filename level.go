@@ -5,7 +5,8 @@ import "sync/atomic"
 type Level int32
 
 const (
-	DebugLevel Level = iota - 1
+	TraceLevel Level = iota - 2
+	DebugLevel
 	InfoLevel
 	WarnLevel
 	ErrorLevel
@@ -13,15 +14,16 @@ const (
 )
 
 var levelNames = [...]string{
-	DebugLevel + 1: "DEBUG",
-	InfoLevel + 1:  "INFO",
-	WarnLevel + 1:  "WARN",
-	ErrorLevel + 1: "ERROR",
-	FatalLevel + 1: "FATAL",
+	TraceLevel - TraceLevel: "TRACE",
+	DebugLevel - TraceLevel: "DEBUG",
+	InfoLevel - TraceLevel:  "INFO",
+	WarnLevel - TraceLevel:  "WARN",
+	ErrorLevel - TraceLevel: "ERROR",
+	FatalLevel - TraceLevel: "FATAL",
 }
 
 func (l Level) String() string {
-	idx := l + 1
+	idx := l - TraceLevel
 	if idx >= 0 && int(idx) < len(levelNames) {
 		return levelNames[idx]
 	}
@@ -10,21 +10,50 @@ import (
 )
 
 type Logger struct {
-	output  io.Writer
-	level   *AtomicLevel
-	pool    *gohotpool.Pool
-	context []byte
+	groups   []sinkGroup
+	level    *AtomicLevel
+	pool     *gohotpool.Pool
+	context  [][]byte // pre-encoded With fields, one per sink group
+	fields   []Field  // the With fields themselves, for hooks and WithRedaction
+	stats    *writeStats
+	sampler  *sampler
+	limiter  *rateLimiter
+	dedup    *deduper
+	recorder *flightRecorder
+	hooks    *hooks
+	redactor *redactor
+
+	// contextOnly is set once context holds With fields that fields lacks,
+	// because they were added before there were hooks or redaction.
+	contextOnly bool
 }
 
 type Config struct {
 	Output     io.Writer
 	Level      Level
 	PoolConfig *gohotpool.Config
+	// Sinks fans every entry out to several outputs, each with its own level
+	// and encoder. Level still gates all of them. When set, Output is ignored.
+	Sinks []Sink
+	// ErrorOutput receives rate-limited reports of internal failures such as
+	// failed writes. Defaults to os.Stderr; set io.Discard to silence.
+	ErrorOutput io.Writer
+	// Sampling, if set, thins out repetitive entries before they are encoded.
+	Sampling *SamplingConfig
+	// DedupWindow, if positive, collapses identical consecutive entries
+	// logged within the window into one line with a "repeated" count.
+	DedupWindow time.Duration
+	// Redaction, if set, is applied to all fields before they are encoded.
+	Redaction *RedactionPolicy
 }
 
 func New(cfg Config) *Logger {
-	if cfg.Output == nil {
-		cfg.Output = SyncWriter(os.Stderr)
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		if cfg.Output == nil {
+			cfg.Output = SyncWriter(os.Stderr)
+		}
+		sinks = []Sink{{Output: cfg.Output, Level: minLevel}}
 	}
 
 	var pool *gohotpool.Pool
@@ -40,11 +69,35 @@ func New(cfg Config) *Logger {
 		})
 	}
 
-	return &Logger{
-		output: cfg.Output,
-		level:  NewAtomicLevel(cfg.Level),
-		pool:   pool,
+	if cfg.ErrorOutput == nil {
+		cfg.ErrorOutput = os.Stderr
+	}
+
+	groups := newSinkGroups(sinks)
+	l := &Logger{
+		groups:  groups,
+		level:   NewAtomicLevel(cfg.Level),
+		pool:    pool,
+		context: make([][]byte, len(groups)),
+		stats:   newWriteStats(cfg.ErrorOutput),
+	}
+	if cfg.Sampling != nil {
+		l.sampler = newSampler(cfg.Sampling)
 	}
+	if cfg.DedupWindow > 0 {
+		l.dedup = newDeduper(cfg.DedupWindow)
+	}
+	if cfg.Redaction != nil {
+		l.redactor = newRedactor(cfg.Redaction)
+	}
+	return l
+}
+
+// withLogger lets With allocate a child and, for the usual single sink
+// group, its context slice in one go.
+type withLogger struct {
+	Logger
+	context [1][]byte
 }
 
 func (l *Logger) With(fields ...Field) *Logger {
@@ -52,32 +105,54 @@ func (l *Logger) With(fields ...Field) *Logger {
 		return l
 	}
 
+	if l.redactor != nil {
+		fields = l.redactor.redact(nil, fields)
+	}
+
+	var child *Logger
+	if len(l.groups) == 1 {
+		w := &withLogger{Logger: *l}
+		child = &w.Logger
+		child.context = w.context[:]
+	} else {
+		c := *l
+		child = &c
+		child.context = make([][]byte, len(l.groups))
+	}
+
+	// Every group's context is encoded into one buffer and copied out with
+	// a single allocation.
+	var arr [8]int
+	ends := arr[:0]
 	buf := l.pool.Get()
-	b := buf.B
-	for _, f := range fields {
-		b = append(b, ',')
-		b = appendField(b, f)
+	buf.B = buf.B[:0]
+	for i := range l.groups {
+		buf.B = append(buf.B, l.context[i]...)
+		buf.B = l.groups[i].appendContext(buf.B, fields)
+		ends = append(ends, len(buf.B))
 	}
-	encoded := make([]byte, len(b))
-	copy(encoded, b)
-	buf.B = b
+	all := make([]byte, len(buf.B))
+	copy(all, buf.B)
 	buf.Reset()
 	l.pool.Put(buf)
-
-	child := &Logger{
-		output: l.output,
-		level:  l.level,
-		pool:   l.pool,
+	start := 0
+	for i, end := range ends {
+		child.context[i] = all[start:end:end]
+		start = end
 	}
 
-	if len(l.context) > 0 {
-		child.context = make([]byte, len(l.context)+len(encoded))
-		copy(child.context, l.context)
-		copy(child.context[len(l.context):], encoded)
-	} else {
-		child.context = encoded
+	// Only hooks and redaction need the fields themselves: hooks get them as
+	// Context and WithRedaction re-encodes them. Other loggers make do with
+	// the encoded context and skip the copy.
+	if l.hooks == nil && l.redactor == nil {
+		child.contextOnly = true
+		return child
+	}
+	child.fields = make([]Field, len(l.fields), len(l.fields)+len(fields))
+	copy(child.fields, l.fields)
+	for _, f := range fields {
+		child.fields = append(child.fields, cloneField(f))
 	}
-
 	return child
 }
 
@@ -85,29 +160,36 @@ func (l *Logger) SetLevel(level Level) {
 	l.level.SetLevel(level)
 }
 
+func (l *Logger) Trace(msg string, fields ...Field) {
+	if !l.enabled(TraceLevel) {
+		return
+	}
+	l.log(TraceLevel, msg, fields)
+}
+
 func (l *Logger) Debug(msg string, fields ...Field) {
-	if !l.level.Enabled(DebugLevel) {
+	if !l.enabled(DebugLevel) {
 		return
 	}
 	l.log(DebugLevel, msg, fields)
 }
 
 func (l *Logger) Info(msg string, fields ...Field) {
-	if !l.level.Enabled(InfoLevel) {
+	if !l.enabled(InfoLevel) {
 		return
 	}
 	l.log(InfoLevel, msg, fields)
 }
 
 func (l *Logger) Warn(msg string, fields ...Field) {
-	if !l.level.Enabled(WarnLevel) {
+	if !l.enabled(WarnLevel) {
 		return
 	}
 	l.log(WarnLevel, msg, fields)
 }
 
 func (l *Logger) Error(msg string, fields ...Field) {
-	if !l.level.Enabled(ErrorLevel) {
+	if !l.enabled(ErrorLevel) {
 		return
 	}
 	l.log(ErrorLevel, msg, fields)
@@ -118,35 +200,95 @@ func (l *Logger) Fatal(msg string, fields ...Field) {
 	os.Exit(1)
 }
 
-func (l *Logger) log(level Level, msg string, fields []Field) {
-	buf := l.pool.Get()
-	b := buf.B
+// Log logs at a level chosen at run time. Unlike Fatal, it doesn't exit.
+func (l *Logger) Log(level Level, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+	l.log(level, msg, fields)
+}
+
+// Enabled reports whether an entry at level would be logged, for callers
+// that want to skip building expensive fields.
+func (l *Logger) Enabled(level Level) bool {
+	return l.enabled(level)
+}
+
+// enabled reports whether an entry at level has anywhere to go: the output,
+// or a flight recorder.
+func (l *Logger) enabled(level Level) bool {
+	return l.level.Enabled(level) || (l.recorder != nil && level >= l.recorder.capture)
+}
 
-	b = append(b, `{"level":"`...)
-	b = append(b, level.String()...)
-	b = append(b, `","ts":`...)
-	b = appendTime(b, time.Now())
-	b = append(b, `,"msg":`...)
-	b = appendJSONString(b, msg)
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	l.logSampled(level, msg, fields, true)
+}
 
-	if len(l.context) > 0 {
-		b = append(b, l.context...)
+// logSampled runs the filtering stages and writes the entry. sample is false
+// when Check has already consulted the sampler.
+func (l *Logger) logSampled(level Level, msg string, fields []Field, sample bool) {
+	if l.recorder != nil {
+		if !l.level.Enabled(level) {
+			l.recorder.record(l, level, msg, fields)
+			return
+		}
+		if level >= l.recorder.trigger {
+			l.recorder.dump(l, level)
+		}
+	}
+	if sample && l.sampler != nil && !l.sampler.allow(level, msg) {
+		return
+	}
+	if l.limiter != nil && !l.limiter.allow(level, fields) {
+		return
+	}
+	if l.dedup != nil && l.dedup.check(l, level, msg, fields) {
+		return
 	}
+	l.write(level, msg, fields)
+}
 
-	for i := range fields {
-		b = append(b, ',')
-		b = appendField(b, fields[i])
+// write encodes and outputs an entry that has passed all filtering stages.
+func (l *Logger) write(level Level, msg string, fields []Field) {
+	ent := Entry{Level: level, Time: time.Now(), Message: msg}
+	if l.hooks != nil {
+		l.hooks.run(l, ent, fields) // redacts after the pre-hooks
+		return
+	}
+	if l.redactor != nil {
+		p := l.redactor.redactPooled(fields)
+		defer releaseFields(p)
+		fields = *p
 	}
+	l.writeEntry(ent, fields)
+}
 
-	b = append(b, '}', '\n')
+func (l *Logger) writeEntry(ent Entry, fields []Field) {
+	buf := l.pool.Get()
 
-	buf.B = b
-	_, _ = l.output.Write(buf.B)
+	for i := range l.groups {
+		g := &l.groups[i]
+		if ent.Level < g.level {
+			continue
+		}
+		buf.B = g.encode(buf.B[:0], ent, l.context[i], fields)
+		l.output(g, ent.Level, buf.B)
+	}
 
 	buf.Reset()
 	l.pool.Put(buf)
 }
 
+// output writes an encoded entry to every sink in g that accepts level.
+func (l *Logger) output(g *sinkGroup, level Level, b []byte) {
+	for j := range g.sinks {
+		if level >= g.sinks[j].Level {
+			n, err := g.sinks[j].Output.Write(b)
+			l.stats.record(n, len(b), err)
+		}
+	}
+}
+
 type syncWriter struct {
 	mu sync.Mutex
 	w  io.Writer
@@ -1,6 +1,7 @@
 package mach
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"testing"
@@ -189,6 +190,32 @@ func BenchmarkWithContext_Mach(b *testing.B) {
 	}
 }
 
+func BenchmarkWith_Mach(b *testing.B) {
+	l := newMachLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = l.With(
+			String("service", "api-gateway"),
+			String("version", "2.4.1"),
+			String("env", "production"),
+		)
+	}
+}
+
+func BenchmarkWith_Zap(b *testing.B) {
+	l := newZapLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = l.With(
+			zap.String("service", "api-gateway"),
+			zap.String("version", "2.4.1"),
+			zap.String("env", "production"),
+		)
+	}
+}
+
 func BenchmarkWithContext_Zap(b *testing.B) {
 	base := newZapLogger()
 	l := base.With(
@@ -238,6 +265,36 @@ func BenchmarkDisabled_Mach(b *testing.B) {
 	}
 }
 
+func BenchmarkSampledOut_Mach(b *testing.B) {
+	l := New(Config{
+		Output:   io.Discard,
+		Level:    DebugLevel,
+		Sampling: &SamplingConfig{First: 1},
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debug("this should be skipped",
+			String("key", "value"),
+			Int("count", 42),
+		)
+	}
+}
+
+// BenchmarkRateLimitedOverflow_Mach logs a new key on every iteration with
+// the per-key limiter full, so each entry lands in the overflow bucket.
+func BenchmarkRateLimitedOverflow_Mach(b *testing.B) {
+	l := New(Config{Output: io.Discard}).RateLimitedBy("key", 1, 1)
+	for i := 0; i < maxRateLimitKeys; i++ {
+		l.Info("fill", Int("key", i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("this should be limited", Int("key", maxRateLimitKeys+i))
+	}
+}
+
 func BenchmarkDisabled_Zap(b *testing.B) {
 	l := newZapLogger().WithOptions(zap.IncreaseLevel(zapcore.ErrorLevel))
 	b.ReportAllocs()
@@ -466,3 +523,97 @@ func BenchmarkParallelWithContext_Slog(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkTee_Mach(b *testing.B) {
+	l := New(Config{
+		Level: DebugLevel,
+		Sinks: []Sink{
+			{Output: io.Discard, Level: DebugLevel, Encoder: ConsoleEncoder()},
+			{Output: io.Discard, Level: InfoLevel},
+			{Output: io.Discard, Level: ErrorLevel},
+		},
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request completed",
+			String("method", "GET"),
+			String("path", "/api/v1/users"),
+			Int("status", 200),
+			Duration("latency", 1532*time.Microsecond),
+		)
+	}
+}
+
+func BenchmarkContextFields_Mach(b *testing.B) {
+	l := newMachLogger()
+	ctx := WithFields(context.Background(),
+		String("request_id", "req-7f3a9c"),
+		String("user_id", "usr_9f8a7b6c"),
+	)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.InfoCtx(ctx, "request handled",
+			String("method", "POST"),
+			Int("status", 201),
+		)
+	}
+}
+
+func BenchmarkSugarInfow_Mach(b *testing.B) {
+	l := newMachLogger().Sugar()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infow("request completed",
+			"method", "GET",
+			"status", 200,
+			"latency", 1500*time.Microsecond,
+		)
+	}
+}
+
+func BenchmarkSugarInfow_Zap(b *testing.B) {
+	l := newZapLogger().Sugar()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infow("request completed",
+			"method", "GET",
+			"status", 200,
+			"latency", 1500*time.Microsecond,
+		)
+	}
+}
+
+func BenchmarkSugarDisabled_Mach(b *testing.B) {
+	l := New(Config{Output: io.Discard, Level: ErrorLevel}).Sugar()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("skipped %s %d", "value", 42)
+	}
+}
+
+func BenchmarkCheck_Mach(b *testing.B) {
+	l := newMachLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ce := l.Check(InfoLevel, "request completed"); ce != nil {
+			ce.Write(String("method", "GET"), Int("status", 200))
+		}
+	}
+}
+
+func BenchmarkCheck_Zap(b *testing.B) {
+	l := newZapLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ce := l.Check(zap.InfoLevel, "request completed"); ce != nil {
+			ce.Write(zap.String("method", "GET"), zap.Int("status", 200))
+		}
+	}
+}
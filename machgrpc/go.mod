@@ -0,0 +1,17 @@
+module github.com/MYK12397/mach/machgrpc
+
+go 1.23.1
+
+require (
+	github.com/MYK12397/mach v0.0.0-20261018135310-7aed51c53b02
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.35.2
+)
+
+require (
+	github.com/MYK12397/gohotpool v1.0.0 // indirect
+	golang.org/x/net v0.32.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+)
@@ -0,0 +1,233 @@
+// Package machgrpc provides gRPC interceptors that log calls through a mach
+// Logger.
+package machgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MYK12397/mach"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type Config struct {
+	// Level picks the level of the entry logged when a call finishes.
+	// Default CodeLevel.
+	Level func(codes.Code) mach.Level
+	// RequestIDKey is the metadata key whose value, if present on an
+	// incoming call, is added to the request logger as request_id. Default
+	// "x-request-id".
+	RequestIDKey string
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Level == nil {
+		cfg.Level = CodeLevel
+	}
+	if cfg.RequestIDKey == "" {
+		cfg.RequestIDKey = "x-request-id"
+	}
+}
+
+// CodeLevel logs OK at Info, codes that point at the caller (NotFound,
+// InvalidArgument, DeadlineExceeded, ...) at Warn, and server-side failures
+// (Unknown, Internal, Unavailable, Unimplemented, DataLoss) at Error.
+func CodeLevel(c codes.Code) mach.Level {
+	switch c {
+	case codes.OK:
+		return mach.InfoLevel
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.Unimplemented, codes.DataLoss:
+		return mach.ErrorLevel
+	}
+	return mach.WarnLevel
+}
+
+// UnaryServerInterceptor gives each call a child of l carrying the method,
+// peer address and request ID, retrievable with mach.FromContext, and logs
+// the call's code and duration when the handler returns.
+func UnaryServerInterceptor(l *mach.Logger, cfg Config) grpc.UnaryServerInterceptor {
+	cfg.setDefaults()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		log := requestLogger(ctx, l, info.FullMethod, cfg.RequestIDKey)
+		resp, err := handler(mach.WithContext(ctx, log), req)
+		logFinished(log, cfg.Level, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams, adding the
+// number of messages sent and received.
+func StreamServerInterceptor(l *mach.Logger, cfg Config) grpc.StreamServerInterceptor {
+	cfg.setDefaults()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		log := requestLogger(ss.Context(), l, info.FullMethod, cfg.RequestIDKey)
+		ws := &serverStream{ServerStream: ss, ctx: mach.WithContext(ss.Context(), log)}
+		err := handler(srv, ws)
+		logFinished(log, cfg.Level, err, time.Since(start),
+			mach.Int64("grpc.msgs_sent", ws.sent.Load()),
+			mach.Int64("grpc.msgs_received", ws.received.Load()),
+		)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs each outgoing call with its method, peer
+// address, code and duration, using the call's context.
+func UnaryClientInterceptor(l *mach.Logger, cfg Config) grpc.UnaryClientInterceptor {
+	cfg.setDefaults()
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		var p peer.Peer
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		logClient(ctx, l, cfg.Level, method, &p, err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor logs each outgoing stream once it ends, which is
+// when RecvMsg returns an error (io.EOF included) or SendMsg fails.
+func StreamClientInterceptor(l *mach.Logger, cfg Config) grpc.StreamClientInterceptor {
+	cfg.setDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs := &clientStream{l: l, cfg: &cfg, ctx: ctx, method: method, start: start}
+		s, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(&cs.peer))...)
+		if err != nil {
+			logClient(ctx, l, cfg.Level, method, &cs.peer, err, time.Since(start))
+			return nil, err
+		}
+		cs.ClientStream = s
+		return cs, nil
+	}
+}
+
+func requestLogger(ctx context.Context, l *mach.Logger, method, idKey string) *mach.Logger {
+	var arr [3]mach.Field
+	fields := append(arr[:0], mach.String("grpc.method", method))
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, mach.String("peer.address", p.Addr.String()))
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(idKey); len(ids) > 0 {
+			fields = append(fields, mach.String("request_id", ids[0]))
+		}
+	}
+	return l.With(fields...)
+}
+
+func logFinished(log *mach.Logger, levelOf func(codes.Code) mach.Level, err error, d time.Duration, extra ...mach.Field) {
+	code := status.Code(err)
+	level := levelOf(code)
+	if !log.Enabled(level) {
+		return
+	}
+	var arr [5]mach.Field
+	fields := append(arr[:0], mach.String("grpc.code", code.String()), mach.Duration("grpc.duration", d))
+	if err != nil {
+		fields = append(fields, mach.Err(err))
+	}
+	log.Log(level, "finished call", append(fields, extra...)...)
+}
+
+func logClient(ctx context.Context, l *mach.Logger, levelOf func(codes.Code) mach.Level, method string, p *peer.Peer, err error, d time.Duration, extra ...mach.Field) {
+	code := status.Code(err)
+	level := levelOf(code)
+	if !l.Enabled(level) {
+		return
+	}
+	var arr [8]mach.Field
+	fields := append(arr[:0], mach.String("grpc.method", method))
+	if p.Addr != nil {
+		fields = append(fields, mach.String("peer.address", p.Addr.String()))
+	}
+	fields = append(fields, mach.String("grpc.code", code.String()), mach.Duration("grpc.duration", d))
+	if err != nil {
+		fields = append(fields, mach.Err(err))
+	}
+	l.LogCtx(ctx, level, "finished client call", append(fields, extra...)...)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	l      *mach.Logger
+	cfg    *Config
+	ctx    context.Context
+	method string
+	start  time.Time
+	peer   peer.Peer
+
+	sent     atomic.Int64
+	received atomic.Int64
+	once     sync.Once
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	} else if !errors.Is(err, io.EOF) {
+		// io.EOF means the stream ended; the real status comes from RecvMsg.
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		s.finish(nil)
+	} else {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		logClient(s.ctx, s.l, s.cfg.Level, s.method, &s.peer, err, time.Since(s.start),
+			mach.Int64("grpc.msgs_sent", s.sent.Load()),
+			mach.Int64("grpc.msgs_received", s.received.Load()),
+		)
+	})
+}
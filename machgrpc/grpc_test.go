@@ -0,0 +1,188 @@
+package machgrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MYK12397/mach"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.b.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %q", err, line)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+var echoDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				mach.FromContext(ctx).Info("echo")
+				if v := req.(*wrapperspb.StringValue).Value; v != "missing" {
+					return wrapperspb.String(v), nil
+				}
+				return nil, status.Error(codes.NotFound, "no such thing")
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Echo"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Chat",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			for {
+				in := new(wrapperspb.StringValue)
+				if err := stream.RecvMsg(in); err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return err
+				}
+				if err := stream.SendMsg(in); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func dial(t *testing.T, serverLog, clientLog *mach.Logger) *grpc.ClientConn {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(serverLog, Config{})),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverLog, Config{})),
+	)
+	srv.RegisterService(&echoDesc, struct{}{})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientLog, Config{})),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(clientLog, Config{})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestUnary(t *testing.T) {
+	var sbuf, cbuf lockedBuffer
+	conn := dial(t, mach.New(mach.Config{Output: &sbuf}), mach.New(mach.Config{Output: &cbuf}))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "r1")
+	out := new(wrapperspb.StringValue)
+	if err := conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), out); err != nil || out.Value != "hi" {
+		t.Fatalf("echo: %v %q", err, out.Value)
+	}
+	err := conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("missing"), out)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("want NotFound, got %v", err)
+	}
+
+	s := sbuf.entries(t)
+	if len(s) != 4 {
+		t.Fatalf("want 4 server entries, got %v", s)
+	}
+	if s[0]["msg"] != "echo" || s[0]["grpc.method"] != "/test.Echo/Echo" || s[0]["request_id"] != "r1" || s[0]["peer.address"] == nil {
+		t.Fatalf("request logger: %v", s[0])
+	}
+	if s[1]["msg"] != "finished call" || s[1]["level"] != "INFO" || s[1]["grpc.code"] != "OK" || s[1]["grpc.duration"] == nil {
+		t.Fatalf("finished: %v", s[1])
+	}
+	if s[3]["level"] != "WARN" || s[3]["grpc.code"] != "NotFound" || s[3]["error"] == nil {
+		t.Fatalf("finished with error: %v", s[3])
+	}
+
+	c := cbuf.entries(t)
+	if len(c) != 2 || c[0]["msg"] != "finished client call" || c[0]["grpc.method"] != "/test.Echo/Echo" ||
+		c[0]["grpc.code"] != "OK" || c[1]["grpc.code"] != "NotFound" {
+		t.Fatalf("client entries: %v", c)
+	}
+}
+
+func TestStream(t *testing.T) {
+	var sbuf, cbuf lockedBuffer
+	conn := dial(t, mach.New(mach.Config{Output: &sbuf}), mach.New(mach.Config{Output: &cbuf}))
+
+	cs, err := conn.NewStream(context.Background(), &echoDesc.Streams[0], "/test.Echo/Chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		if err := cs.SendMsg(wrapperspb.String(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cs.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for {
+		if err := cs.RecvMsg(new(wrapperspb.StringValue)); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatal(err)
+			}
+			break
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("received %d", n)
+	}
+
+	s := sbuf.entries(t)
+	if len(s) != 1 || s[0]["grpc.code"] != "OK" || s[0]["grpc.msgs_sent"] != 3.0 || s[0]["grpc.msgs_received"] != 3.0 {
+		t.Fatalf("server entries: %v", s)
+	}
+	c := cbuf.entries(t)
+	if len(c) != 1 || c[0]["grpc.method"] != "/test.Echo/Chat" || c[0]["grpc.msgs_sent"] != 3.0 || c[0]["grpc.msgs_received"] != 3.0 {
+		t.Fatalf("client entries: %v", c)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/MYK12397/mach/machlogr
+
+go 1.23.1
+
+require (
+	github.com/MYK12397/mach v0.0.0-20261018135310-7aed51c53b02
+	github.com/go-logr/logr v1.4.2
+)
+
+require (
+	github.com/MYK12397/gohotpool v1.0.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
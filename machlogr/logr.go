@@ -0,0 +1,96 @@
+// Package machlogr implements a logr.LogSink backed by a mach Logger, for
+// libraries such as the Kubernetes clients that log through logr.
+package machlogr
+
+import (
+	"fmt"
+
+	"github.com/MYK12397/mach"
+	"github.com/go-logr/logr"
+)
+
+// New returns a logr.Logger writing through l.
+func New(l *mach.Logger) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// LogSink maps logr V-levels onto mach levels: V(0) is Info, V(1) Debug and
+// V(2) and above Trace. Values and names are added with mach's With, so they
+// are encoded once rather than on every entry.
+type LogSink struct {
+	base *mach.Logger // with values, without the name
+	l    *mach.Logger // base plus the "logger" name field
+	name string
+}
+
+func NewLogSink(l *mach.Logger) *LogSink {
+	return &LogSink{base: l, l: l}
+}
+
+func (s *LogSink) Init(logr.RuntimeInfo) {}
+
+func (s *LogSink) Enabled(level int) bool {
+	return s.l.Enabled(vLevel(level))
+}
+
+func (s *LogSink) Info(level int, msg string, keysAndValues ...any) {
+	var arr [16]mach.Field
+	s.l.Log(vLevel(level), msg, appendFields(arr[:0], keysAndValues)...)
+}
+
+func (s *LogSink) Error(err error, msg string, keysAndValues ...any) {
+	var arr [16]mach.Field
+	fields := arr[:0]
+	if err != nil {
+		fields = append(fields, mach.Err(err))
+	}
+	s.l.Log(mach.ErrorLevel, msg, appendFields(fields, keysAndValues)...)
+}
+
+func (s *LogSink) WithValues(keysAndValues ...any) logr.LogSink {
+	var arr [16]mach.Field
+	child := &LogSink{base: s.base.With(appendFields(arr[:0], keysAndValues)...), name: s.name}
+	child.l = child.base
+	if child.name != "" {
+		child.l = child.base.With(mach.String("logger", child.name))
+	}
+	return child
+}
+
+// WithName appends name to the logger's name, joined with "/" like
+// logr/funcr, and logs it as the "logger" field.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	return &LogSink{base: s.base, l: s.base.With(mach.String("logger", name)), name: name}
+}
+
+func vLevel(v int) mach.Level {
+	switch {
+	case v <= 0:
+		return mach.InfoLevel
+	case v == 1:
+		return mach.DebugLevel
+	}
+	return mach.TraceLevel
+}
+
+func appendFields(dst []mach.Field, kv []any) []mach.Field {
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 == len(kv) {
+			dst = append(dst, mach.String(key, "<no-value>"))
+			break
+		}
+		v := kv[i+1]
+		if m, ok := v.(logr.Marshaler); ok {
+			v = m.MarshalLog()
+		}
+		dst = append(dst, mach.Any(key, v))
+	}
+	return dst
+}
@@ -0,0 +1,66 @@
+package machlogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MYK12397/mach"
+)
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %q", err, line)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestLogSink(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(mach.New(mach.Config{Output: &buf, Level: mach.DebugLevel}))
+
+	ctrl := log.WithName("controller").WithValues("ns", "default").WithName("pods")
+	ctrl.Info("synced", "count", 3, "took", 2*time.Second, "ready", true)
+	ctrl.V(1).Info("debug detail")
+	ctrl.V(2).Info("trace detail") // below the logger's level
+	ctrl.Error(errors.New("boom"), "sync failed", "retry", uint8(2), "dangling")
+
+	e := entries(t, &buf)
+	if len(e) != 3 {
+		t.Fatalf("want 3 entries, got %q", buf.String())
+	}
+	if e[0]["level"] != "INFO" || e[0]["logger"] != "controller/pods" || e[0]["ns"] != "default" ||
+		e[0]["count"] != 3.0 || e[0]["took"] != 2.0 || e[0]["ready"] != true {
+		t.Fatalf("info entry %v", e[0])
+	}
+	if e[1]["level"] != "DEBUG" || e[1]["msg"] != "debug detail" {
+		t.Fatalf("V(1) entry %v", e[1])
+	}
+	if e[2]["level"] != "ERROR" || e[2]["error"] != "boom" || e[2]["retry"] != 2.0 || e[2]["dangling"] != "<no-value>" {
+		t.Fatalf("error entry %v", e[2])
+	}
+	if strings.Count(buf.String(), `"logger"`) != 3 {
+		t.Fatalf("name field repeated: %q", buf.String())
+	}
+}
+
+func TestTraceLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(mach.New(mach.Config{Output: &buf, Level: mach.TraceLevel}))
+	if !log.V(5).Enabled() {
+		t.Fatal("V(5) should be enabled at TraceLevel")
+	}
+	log.V(2).Info("deep")
+	if e := entries(t, &buf); e[0]["level"] != "TRACE" {
+		t.Fatalf("entry %v", e[0])
+	}
+}
@@ -0,0 +1,179 @@
+package mach
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type HTTPConfig struct {
+	// RequestIDHeader carries the request ID. An incoming value is reused,
+	// otherwise one is generated, and it is echoed on the response. Default
+	// "X-Request-Id".
+	RequestIDHeader string
+	// AccessLogger receives the access entries. Default is the logger passed
+	// to HTTPMiddleware; use a separate one to send them to their own sink,
+	// e.g. with CombinedLogEncoder.
+	AccessLogger *Logger
+	// Level picks the access entry's level. Default: Error for 5xx, Warn for
+	// 4xx, Info otherwise.
+	Level func(status int) Level
+}
+
+// HTTPMiddleware returns middleware that gives each request a child of l
+// carrying its request ID, method, path and remote address, retrievable with
+// FromContext(r.Context()), and logs an access entry once the handler
+// returns, or panics.
+func HTTPMiddleware(l *Logger, cfg HTTPConfig) func(http.Handler) http.Handler {
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = "X-Request-Id"
+	}
+	if cfg.AccessLogger == nil {
+		cfg.AccessLogger = l
+	}
+	if cfg.Level == nil {
+		cfg.Level = statusLevel
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(cfg.RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(cfg.RequestIDHeader, id)
+
+			reqLog := l.With(
+				String("request_id", id),
+				String("method", r.Method),
+				String("path", r.URL.Path),
+				String("remote_addr", r.RemoteAddr),
+			)
+			rw := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				// A panicking handler still gets an access entry, as a 500;
+				// net/http recovers the re-raised panic as usual.
+				p := recover()
+				status := rw.status
+				if p != nil {
+					status = http.StatusInternalServerError
+				} else if status == 0 {
+					status = http.StatusOK
+				}
+				if level := cfg.Level(status); cfg.AccessLogger.enabled(level) {
+					logAccess(cfg.AccessLogger, level, r, id, status, rw.bytes, time.Since(start), p)
+				}
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r.WithContext(WithContext(r.Context(), reqLog)))
+		})
+	}
+}
+
+func logAccess(l *Logger, level Level, r *http.Request, id string, status int, n int64, latency time.Duration, panicked any) {
+	var arr [11]Field
+	fields := append(arr[:0],
+		String("request_id", id),
+		String("method", r.Method),
+		String("path", r.URL.Path),
+		String("remote_addr", r.RemoteAddr),
+		String("proto", r.Proto),
+		Int("status", status),
+		Int64("bytes", n),
+		Duration("latency", latency),
+		String("referer", r.Referer()),
+		String("user_agent", r.UserAgent()),
+	)
+	if panicked != nil {
+		fields = append(fields, Any("panic", panicked))
+	}
+	l.log(level, "request", fields)
+}
+
+func statusLevel(status int) Level {
+	switch {
+	case status >= 500:
+		return ErrorLevel
+	case status >= 400:
+		return WarnLevel
+	}
+	return InfoLevel
+}
+
+var (
+	requestIDPrefix = newRequestIDPrefix()
+	requestIDSeq    atomic.Uint64
+)
+
+func newRequestIDPrefix() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// newRequestID returns a process-unique ID: a random per-process prefix and
+// a sequence number.
+func newRequestID() string {
+	b := make([]byte, 0, len(requestIDPrefix)+21)
+	b = append(b, requestIDPrefix...)
+	b = append(b, '-')
+	b = strconv.AppendUint(b, requestIDSeq.Add(1), 10)
+	return string(b)
+}
+
+// responseRecorder captures the status and body size a handler writes.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	// Informational responses other than 101 precede the real status.
+	if w.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("mach: response writer does not support hijacking")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
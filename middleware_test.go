@@ -0,0 +1,99 @@
+package mach
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	var app, access bytes.Buffer
+	log := New(Config{Output: &app})
+	accessLog := New(Config{Sinks: []Sink{
+		{Output: &app},
+		{Output: &access, Encoder: CombinedLogEncoder()},
+	}})
+
+	h := HTTPMiddleware(log, HTTPConfig{AccessLogger: accessLog})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest("GET", "/pot?x=1", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("User-Agent", `curl "8"`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-Id"); got != "abc" {
+		t.Fatalf("response request id %q", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(app.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %q", app.String())
+	}
+	var inner, entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &inner); err != nil {
+		t.Fatal(err)
+	}
+	if inner["msg"] != "handling" || inner["request_id"] != "abc" || inner["method"] != "GET" ||
+		inner["path"] != "/pot" || inner["remote_addr"] != "192.0.2.1:1234" {
+		t.Fatalf("request logger fields: %v", inner)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "WARN" || entry["status"] != 418.0 || entry["bytes"] != 15.0 || entry["request_id"] != "abc" {
+		t.Fatalf("access entry: %v", entry)
+	}
+	if _, ok := entry["latency"].(float64); !ok {
+		t.Fatalf("latency missing: %v", entry)
+	}
+
+	want := regexp.MustCompile(`^192\.0\.2\.1 - - \[\d\d/\w{3}/\d{4}:\d\d:\d\d:\d\d [+-]\d{4}\] "GET /pot HTTP/1\.1" 418 15 "-" "curl \\"8\\""\n$`)
+	if !want.MatchString(access.String()) {
+		t.Fatalf("combined line %q", access.String())
+	}
+}
+
+func TestHTTPMiddlewareGeneratesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	h := HTTPMiddleware(New(Config{Output: &buf}), HTTPConfig{})(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	id := rec.Header().Get("X-Request-Id")
+	if id == "" || !strings.Contains(buf.String(), `"request_id":"`+id+`"`) {
+		t.Fatalf("id %q, log %q", id, buf.String())
+	}
+}
+
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	var buf bytes.Buffer
+	h := HTTPMiddleware(New(Config{Output: &buf}), HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("panic not re-raised for net/http: %v", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/crash", nil))
+	}()
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if entry["level"] != "ERROR" || entry["status"] != 500.0 || entry["panic"] != "boom" || entry["path"] != "/crash" {
+		t.Fatalf("access entry: %v", entry)
+	}
+}
@@ -0,0 +1,352 @@
+package mach
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errNetBufferFull = errors.New("mach: network writer buffer full")
+	errNetUnsent     = errors.New("mach: network writer closed with unsent entries")
+)
+
+type NetConfig struct {
+	Network      string // "tcp" or "unix"
+	Addr         string
+	TLSConfig    *tls.Config
+	DialTimeout  time.Duration // default 5s
+	WriteTimeout time.Duration // default 10s
+	MinBackoff   time.Duration // default 100ms
+	MaxBackoff   time.Duration // default 30s
+
+	// BufferSize bounds the bytes held in memory while the collector is
+	// unreachable. Default 4 MB.
+	BufferSize int
+	// SpoolPath, when set, is a file that takes entries once the memory
+	// buffer is full. It is replayed in order when the connection returns,
+	// including on the next start if the process exits with data spooled.
+	SpoolPath    string
+	MaxSpoolSize int64 // default 256 MB
+}
+
+// NetWriter ships newline-delimited entries over a stream connection. Write
+// only copies into a buffer; a background goroutine owns the connection,
+// reconnecting with exponential backoff after failures.
+type NetWriter struct {
+	cfg NetConfig
+
+	mu      sync.Mutex
+	pending []byte
+	spare   []byte
+	spool   *os.File
+	spoolR  int64
+	spoolW  int64
+	closed  bool
+	dropped atomic.Uint64
+
+	conn net.Conn // owned by run
+	wake chan struct{}
+	quit chan struct{}
+	done chan struct{}
+}
+
+func NewNetWriter(cfg NetConfig) (*NetWriter, error) {
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 4 << 20
+	}
+	if cfg.MaxSpoolSize == 0 {
+		cfg.MaxSpoolSize = 256 << 20
+	}
+
+	w := &NetWriter{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if cfg.SpoolPath != "" {
+		f, err := os.OpenFile(cfg.SpoolPath, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		w.spool = f
+		w.spoolW = info.Size()
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	// Once anything is spooled, new entries follow it to disk so that the
+	// replay keeps them in order.
+	switch {
+	case w.spoolW == w.spoolR && len(w.pending)+len(p) <= w.cfg.BufferSize:
+		w.pending = append(w.pending, p...)
+	case w.spool != nil && w.spoolW+int64(len(p)) <= w.cfg.MaxSpoolSize:
+		n, err := w.spool.WriteAt(p, w.spoolW)
+		w.spoolW += int64(n)
+		if err != nil {
+			return n, err
+		}
+	default:
+		w.dropped.Add(1)
+		return 0, errNetBufferFull
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped reports how many entries were discarded because both the memory
+// buffer and the spool were full, or because they were still unsent at Close
+// with no spool to keep them.
+func (w *NetWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close sends buffered entries until none are left or a send fails, dialling
+// once more if the connection is down. Whatever could not be sent is moved to
+// the spool, if configured, and otherwise dropped, in which case Close
+// reports it.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.quit)
+	<-w.done
+
+	if w.spool == nil {
+		if lost := bytes.Count(w.pending, []byte{'\n'}); lost > 0 {
+			w.dropped.Add(uint64(lost))
+			return errNetUnsent
+		}
+		return nil
+	}
+	err := w.compactSpool()
+	if cerr := w.spool.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// compactSpool rewrites the spool as the unsent memory buffer followed by the
+// unreplayed part of the file, so the next start replays from offset zero.
+func (w *NetWriter) compactSpool() error {
+	if w.spoolR == 0 && len(w.pending) == 0 {
+		return nil
+	}
+	data := make([]byte, len(w.pending)+int(w.spoolW-w.spoolR))
+	copy(data, w.pending)
+	if _, err := w.spool.ReadAt(data[len(w.pending):], w.spoolR); err != nil {
+		return err
+	}
+	if _, err := w.spool.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return w.spool.Truncate(int64(len(data)))
+}
+
+func (w *NetWriter) run() {
+	defer close(w.done)
+
+	w.loop()
+	w.drain()
+	if w.conn != nil {
+		_ = w.conn.Close()
+	}
+}
+
+// loop ships entries as they arrive until Close.
+func (w *NetWriter) loop() {
+	backoff := w.cfg.MinBackoff
+	for {
+		if !w.hasData() {
+			select {
+			case <-w.wake:
+				continue
+			case <-w.quit:
+				return
+			}
+		}
+
+		if w.conn == nil {
+			if err := w.dial(); err != nil {
+				select {
+				case <-time.After(backoff):
+				case <-w.quit:
+					return
+				}
+				backoff = min(backoff*2, w.cfg.MaxBackoff)
+				continue
+			}
+			backoff = w.cfg.MinBackoff
+		}
+
+		if err := w.flush(); err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+	}
+}
+
+// drain is the last pass after Close. Write no longer accepts entries, so it
+// sends what is left until nothing remains or a send fails, without backoff.
+func (w *NetWriter) drain() {
+	for w.hasData() {
+		if w.conn == nil && w.dial() != nil {
+			return
+		}
+		if err := w.flush(); err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+			return
+		}
+	}
+}
+
+func (w *NetWriter) hasData() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending) > 0 || w.spoolR < w.spoolW
+}
+
+func (w *NetWriter) dial() error {
+	d := net.Dialer{Timeout: w.cfg.DialTimeout}
+	var err error
+	if w.cfg.TLSConfig != nil {
+		w.conn, err = tls.DialWithDialer(&d, w.cfg.Network, w.cfg.Addr, w.cfg.TLSConfig)
+	} else {
+		w.conn, err = d.Dial(w.cfg.Network, w.cfg.Addr)
+	}
+	if err != nil {
+		w.conn = nil
+	}
+	return err
+}
+
+func (w *NetWriter) send(p []byte) (int, error) {
+	_ = w.conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+	return w.conn.Write(p)
+}
+
+// flush sends the in-memory buffer and then replays the spool. On a failed
+// write the unsent tail is kept, rewound to the start of the interrupted
+// line so the collector never sees half an entry on the new connection.
+func (w *NetWriter) flush() error {
+	w.mu.Lock()
+	batch := w.pending
+	w.pending, w.spare = w.spare[:0], nil
+	w.mu.Unlock()
+
+	if len(batch) > 0 {
+		n, err := w.send(batch)
+		if err != nil {
+			rest := batch[lineStart(batch, n):]
+			w.mu.Lock()
+			w.pending = append(append(make([]byte, 0, len(rest)+len(w.pending)), rest...), w.pending...)
+			w.mu.Unlock()
+			return err
+		}
+	}
+	w.mu.Lock()
+	w.spare = batch[:0]
+	w.mu.Unlock()
+
+	return w.replaySpool()
+}
+
+const spoolChunk = 64 << 10
+
+func (w *NetWriter) replaySpool() error {
+	var chunk []byte
+	for {
+		w.mu.Lock()
+		if w.spoolR == w.spoolW {
+			if w.spoolW > 0 {
+				_ = w.spool.Truncate(0)
+				w.spoolR, w.spoolW = 0, 0
+			}
+			w.mu.Unlock()
+			return nil
+		}
+		off := w.spoolR
+		size := min(w.spoolW-off, spoolChunk)
+		more := off+size < w.spoolW
+		w.mu.Unlock()
+
+		if chunk == nil {
+			chunk = make([]byte, spoolChunk)
+		}
+		n, err := w.spool.ReadAt(chunk[:size], off)
+		if int64(n) < size {
+			return err
+		}
+		b := chunk[:n]
+		if more {
+			if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+				b = b[:i+1]
+			}
+		}
+
+		sent, err := w.send(b)
+		if err != nil {
+			sent = lineStart(b, sent)
+		}
+		w.mu.Lock()
+		w.spoolR += int64(sent)
+		w.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lineStart returns the offset of the line containing b[n].
+func lineStart(b []byte, n int) int {
+	if i := bytes.LastIndexByte(b[:n], '\n'); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
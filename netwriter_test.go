@@ -0,0 +1,188 @@
+package mach
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetWriterSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "collector.sock")
+
+	w, err := NewNetWriter(NetConfig{
+		Network:    "unix",
+		Addr:       sock,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+		BufferSize: 256,
+		SpoolPath:  filepath.Join(dir, "spool.ndjson"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	const n = 50
+	log := New(Config{Output: w})
+	for i := 0; i < n; i++ {
+		log.Info("entry", Int("i", i))
+	}
+	if w.Dropped() != 0 {
+		t.Fatalf("dropped %d entries", w.Dropped())
+	}
+
+	// The collector only comes up after everything has been buffered.
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	sc := bufio.NewScanner(c)
+	for i := 0; i < n; i++ {
+		if !sc.Scan() {
+			t.Fatalf("entry %d: %v", i, sc.Err())
+		}
+		want := fmt.Sprintf(`,"msg":"entry","i":%d}`, i)
+		line := sc.Text()
+		if len(line) < len(want) || line[len(line)-len(want):] != want {
+			t.Fatalf("entry %d out of order: %s", i, line)
+		}
+	}
+}
+
+// listenUnix starts a collector on a Unix socket in a temporary directory.
+func listenUnix(t *testing.T) (*net.UnixListener, string) {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "collector.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: sock, Net: "unix"})
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	_ = ln.SetDeadline(time.Now().Add(5 * time.Second))
+	return ln, sock
+}
+
+func TestNetWriterCloseDrains(t *testing.T) {
+	big := append(bytes.Repeat([]byte{'x'}, 1<<20), '\n')
+	for i := 0; i < 10; i++ {
+		ln, sock := listenUnix(t)
+		got := make(chan []byte, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				got <- nil
+				return
+			}
+			defer c.Close()
+			b, _ := io.ReadAll(c)
+			got <- b
+		}()
+
+		w, err := NewNetWriter(NetConfig{Network: "unix", Addr: sock})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(big); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("last\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if b := <-got; len(b) != len(big)+5 || !bytes.HasSuffix(b, []byte("x\nlast\n")) {
+			t.Fatalf("run %d: collector got %d bytes, want %d", i, len(b), len(big)+5)
+		}
+		if _, err := w.Write([]byte("late\n")); err == nil {
+			t.Fatal("Write after Close succeeded")
+		}
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	ln, sock := listenUnix(t)
+	w, err := NewNetWriter(NetConfig{
+		Network:    "unix",
+		Addr:       sock,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	log := New(Config{Output: w})
+
+	log.Info("first")
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := bufio.NewReader(c).ReadString('\n'); !strings.Contains(line, `"msg":"first"`) {
+		t.Fatalf("first connection got %q, %v", line, err)
+	}
+	c.Close()
+
+	// The write to the dropped connection fails and the entry is resent
+	// on the next one.
+	log.Info("second")
+	c, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := bufio.NewReader(c).ReadString('\n'); !strings.Contains(line, `"msg":"second"`) {
+		t.Fatalf("second connection got %q, %v", line, err)
+	}
+}
+
+func TestNetWriterDropped(t *testing.T) {
+	w, err := NewNetWriter(NetConfig{
+		Network:    "unix",
+		Addr:       filepath.Join(t.TempDir(), "nobody.sock"),
+		MinBackoff: time.Hour,
+		BufferSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(strings.Repeat("a", 39) + "\n")
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Write(line); !errors.Is(err, errNetBufferFull) {
+		t.Fatalf("third write: %v", err)
+	}
+	if n := w.Dropped(); n != 1 {
+		t.Fatalf("Dropped() = %d, want 1", n)
+	}
+
+	// Nothing can be sent and there is no spool, so Close drops the rest.
+	if err := w.Close(); !errors.Is(err, errNetUnsent) {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := w.Dropped(); n != 3 {
+		t.Fatalf("Dropped() after Close = %d, want 3", n)
+	}
+}
@@ -0,0 +1,382 @@
+package mach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type otlpEncoder struct{}
+
+var otlpEnc = &otlpEncoder{}
+
+// OTLPEncoder returns an encoder that writes each entry as one line holding
+// an OpenTelemetry ResourceLogs object in the OTLP/JSON encoding. With fields
+// become resource attributes and per-call fields log record attributes. Trace
+// correlation fields (see SetTraceFieldNames) fill traceId, spanId and flags.
+func OTLPEncoder() EntryEncoder { return otlpEnc }
+
+func (*otlpEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	for i := range fields {
+		dst = append(dst, ',')
+		dst = appendOTLPAttribute(dst, fields[i])
+	}
+	return dst
+}
+
+func (*otlpEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	dst = append(dst, `{"resource":{"attributes":[`...)
+	if len(context) > 0 {
+		dst = append(dst, context[1:]...)
+	}
+	dst = append(dst, `]},"scopeLogs":[{"scope":{"name":"mach"},"logRecords":[{"timeUnixNano":"`...)
+	dst = appendInt64(dst, ent.Time.UnixNano())
+	dst = append(dst, `","observedTimeUnixNano":"`...)
+	dst = appendInt64(dst, ent.Time.UnixNano())
+	dst = append(dst, `","severityNumber":`...)
+	dst = appendInt64(dst, otlpSeverity(ent.Level))
+	dst = append(dst, `,"severityText":"`...)
+	dst = append(dst, ent.Level.String()...)
+	dst = append(dst, `","body":{"stringValue":`...)
+	dst = appendJSONString(dst, ent.Message)
+	dst = append(dst, `},"attributes":[`...)
+
+	names := traceFieldNames.Load()
+	var traceID, spanID, flags string
+	n := 0
+	for i := range fields {
+		f := &fields[i]
+		if f.Type == StringType {
+			switch f.Key {
+			case names.TraceID:
+				if len(f.Str) == 32 {
+					traceID = f.Str
+					continue
+				}
+			case names.SpanID:
+				if len(f.Str) == 16 {
+					spanID = f.Str
+					continue
+				}
+			case names.TraceFlags:
+				if len(f.Str) == 2 {
+					flags = f.Str
+					continue
+				}
+			}
+		}
+		if n > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendOTLPAttribute(dst, *f)
+		n++
+	}
+	dst = append(dst, ']')
+
+	if traceID != "" {
+		dst = append(dst, `,"traceId":"`...)
+		dst = append(dst, traceID...)
+		dst = append(dst, '"')
+	}
+	if spanID != "" {
+		dst = append(dst, `,"spanId":"`...)
+		dst = append(dst, spanID...)
+		dst = append(dst, '"')
+	}
+	var fl [1]byte
+	if flags != "" && decodeLowerHex(fl[:], flags) {
+		dst = append(dst, `,"flags":`...)
+		dst = appendInt64(dst, int64(fl[0]))
+	}
+	return append(dst, "}]}]}\n"...)
+}
+
+// otlpSeverity maps a Level onto the first number of the matching OTLP
+// severity range.
+func otlpSeverity(l Level) int64 {
+	switch {
+	case l >= FatalLevel:
+		return 21
+	case l == ErrorLevel:
+		return 17
+	case l == WarnLevel:
+		return 13
+	case l == InfoLevel:
+		return 9
+	case l == DebugLevel:
+		return 5
+	}
+	return 1
+}
+
+func appendOTLPAttribute(dst []byte, f Field) []byte {
+	dst = append(dst, `{"key":`...)
+	dst = appendJSONString(dst, f.Key)
+	dst = append(dst, `,"value":{`...)
+	switch f.Type {
+	case StringType, ErrorType:
+		dst = append(dst, `"stringValue":`...)
+		dst = appendJSONString(dst, f.Str)
+	case IntType, Int64Type:
+		// 64-bit integers are strings in the protobuf JSON mapping.
+		dst = append(dst, `"intValue":"`...)
+		dst = appendInt64(dst, f.Ival)
+		dst = append(dst, '"')
+	case Float64Type:
+		dst = append(dst, `"doubleValue":`...)
+		dst = appendFloat64(dst, math.Float64frombits(uint64(f.Ival)))
+	case BoolType:
+		dst = append(dst, `"boolValue":`...)
+		dst = appendBool(dst, f.Ival == 1)
+	case DurationType:
+		dst = append(dst, `"doubleValue":`...)
+		dst = appendDuration(dst, time.Duration(f.Ival))
+	case TimeType:
+		dst = append(dst, `"stringValue":`...)
+		dst = appendTime(dst, time.Unix(0, f.Ival))
+	case BytesType:
+		dst = append(dst, `"stringValue":`...)
+		dst = appendJSONString(dst, string(f.Bval))
+	}
+	return append(dst, '}', '}')
+}
+
+var errOTLPBufferFull = errors.New("mach: OTLP exporter buffer full")
+
+type OTLPConfig struct {
+	// Endpoint is the full URL of the collector's logs endpoint. Default
+	// "http://localhost:4318/v1/logs".
+	Endpoint string
+	Headers  map[string]string // e.g. authentication
+	Client   *http.Client      // default has a 10s timeout
+
+	BatchSize     int           // waiting entries that trigger a request, default 512
+	FlushInterval time.Duration // default 1s
+	// BufferSize bounds the bytes waiting for export. Entries written while
+	// it is full are dropped. Default 4 MB.
+	BufferSize int
+	// MaxRetries bounds the retries of a request that failed with a network
+	// error or a retryable status (429, 502, 503, 504). Default 3.
+	MaxRetries int
+}
+
+// OTLPExporter batches lines from OTLPEncoder and posts them to an
+// OTLP/HTTP collector. Write only copies into the batch; a background
+// goroutine sends it when BatchSize entries are waiting or every
+// FlushInterval.
+type OTLPExporter struct {
+	cfg OTLPConfig
+
+	mu      sync.Mutex
+	batch   []byte // ResourceLogs objects separated by commas
+	count   int
+	spare   []byte
+	closed  bool
+	dropped atomic.Uint64
+
+	exportMu sync.Mutex // serializes requests
+	wake     chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	closeErr error
+}
+
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "http://localhost:4318/v1/logs"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 4 << 20
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+
+	e := &OTLPExporter{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Encoder returns OTLPEncoder.
+func (e *OTLPExporter) Encoder() EntryEncoder { return otlpEnc }
+
+// Sink returns a Sink writing to e with OTLPEncoder.
+func (e *OTLPExporter) Sink(level Level) Sink {
+	return Sink{Output: e, Level: level, Encoder: otlpEnc}
+}
+
+func (e *OTLPExporter) Write(p []byte) (int, error) {
+	rec := p
+	if n := len(rec); n > 0 && rec[n-1] == '\n' {
+		rec = rec[:n-1]
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return 0, os.ErrClosed
+	}
+	if len(e.batch)+len(rec)+1 > e.cfg.BufferSize {
+		e.dropped.Add(1)
+		return 0, errOTLPBufferFull
+	}
+	if e.count > 0 {
+		e.batch = append(e.batch, ',')
+	}
+	e.batch = append(e.batch, rec...)
+	e.count++
+
+	if e.count >= e.cfg.BatchSize {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Dropped reports how many entries were lost, either because the buffer was
+// full or because their export failed.
+func (e *OTLPExporter) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// Flush exports everything written so far and waits for the request.
+func (e *OTLPExporter) Flush() error {
+	return e.export()
+}
+
+// Close stops the background goroutine after a final export, which is not
+// retried.
+func (e *OTLPExporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.quit)
+	<-e.done
+	return e.closeErr
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	t := time.NewTicker(e.cfg.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-e.wake:
+		case <-t.C:
+		case <-e.quit:
+			e.closeErr = e.export()
+			return
+		}
+		_ = e.export()
+	}
+}
+
+func (e *OTLPExporter) export() error {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	e.mu.Lock()
+	batch, count := e.batch, e.count
+	e.batch, e.spare, e.count = e.spare[:0], nil, 0
+	e.mu.Unlock()
+
+	if count == 0 {
+		e.mu.Lock()
+		e.spare = batch[:0]
+		e.mu.Unlock()
+		return nil
+	}
+
+	// The body gets its own buffer because the transport may still hold it
+	// after the response arrives.
+	body := make([]byte, 0, len(batch)+len(`{"resourceLogs":[]}`))
+	body = append(body, `{"resourceLogs":[`...)
+	body = append(body, batch...)
+	body = append(body, ']', '}')
+
+	e.mu.Lock()
+	e.spare = batch[:0]
+	e.mu.Unlock()
+
+	err := e.post(body)
+	if err != nil {
+		e.dropped.Add(uint64(count))
+	}
+	return err
+}
+
+func (e *OTLPExporter) post(body []byte) error {
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		retry, err := e.send(body)
+		if err == nil || !retry || attempt >= e.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-e.quit:
+			// Don't hold up Close with backoff.
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// send makes one request and reports whether a failure is worth retrying.
+func (e *OTLPExporter) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("mach: OTLP export: %s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, err
+	}
+	return false, err
+}
@@ -0,0 +1,142 @@
+package mach
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []struct {
+				TimeUnixNano   string          `json:"timeUnixNano"`
+				SeverityNumber int             `json:"severityNumber"`
+				SeverityText   string          `json:"severityText"`
+				Body           map[string]any  `json:"body"`
+				Attributes     []otlpAttribute `json:"attributes"`
+				TraceID        string          `json:"traceId"`
+				SpanID         string          `json:"spanId"`
+				Flags          int             `json:"flags"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" ||
+			r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("bad body %s: %v", body, err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(OTLPConfig{
+		Endpoint:      srv.URL + "/v1/logs",
+		Headers:       map[string]string{"Authorization": "Bearer k"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	log := New(Config{Level: DebugLevel, Sinks: []Sink{exp.Sink(DebugLevel)}}).
+		With(String("service.name", "api"))
+
+	tc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithTrace(context.Background(), tc)
+	log.InfoCtx(ctx, "hello", Int("n", 7))
+	log.Warn("careful", Bool("ok", false), Duration("took", 1500*time.Millisecond))
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		n := len(reqs)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("full batch was not sent")
+		}
+	}
+	log.Error("third")
+	if err := exp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 2 || len(reqs[0].ResourceLogs) != 2 || len(reqs[1].ResourceLogs) != 1 {
+		t.Fatalf("want batches of 2 and 1, got %+v", reqs)
+	}
+
+	rl := reqs[0].ResourceLogs[0]
+	if a := rl.Resource.Attributes; len(a) != 1 || a[0].Key != "service.name" || a[0].Value["stringValue"] != "api" {
+		t.Fatalf("resource attributes %+v", a)
+	}
+	rec := rl.ScopeLogs[0].LogRecords[0]
+	if rec.SeverityNumber != 9 || rec.SeverityText != "INFO" || rec.Body["stringValue"] != "hello" {
+		t.Fatalf("record %+v", rec)
+	}
+	if len(rec.Attributes) != 1 || rec.Attributes[0].Key != "n" || rec.Attributes[0].Value["intValue"] != "7" {
+		t.Fatalf("attributes %+v", rec.Attributes)
+	}
+	if rec.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || rec.SpanID != "00f067aa0ba902b7" || rec.Flags != 1 {
+		t.Fatalf("trace %q %q %d", rec.TraceID, rec.SpanID, rec.Flags)
+	}
+
+	rec = reqs[0].ResourceLogs[1].ScopeLogs[0].LogRecords[0]
+	if rec.SeverityNumber != 13 || rec.Attributes[0].Value["boolValue"] != false || rec.Attributes[1].Value["doubleValue"] != 1.5 {
+		t.Fatalf("record %+v", rec)
+	}
+	if rec := reqs[1].ResourceLogs[0].ScopeLogs[0].LogRecords[0]; rec.SeverityNumber != 17 || len(rec.Attributes) != 0 {
+		t.Fatalf("record %+v", rec)
+	}
+}
+
+func TestOTLPExporterRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(OTLPConfig{Endpoint: srv.URL, FlushInterval: time.Hour})
+	defer exp.Close()
+	log := New(Config{Sinks: []Sink{exp.Sink(InfoLevel)}})
+	log.Info("x")
+	if err := exp.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || exp.Dropped() != 0 {
+		t.Fatalf("calls=%d dropped=%d", calls, exp.Dropped())
+	}
+}
@@ -0,0 +1,173 @@
+package mach
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateLimitKeys bounds the buckets kept by a per-key limiter. When it is
+// reached, idle buckets are swept, at most once per refill period, and new
+// keys share one overflow bucket until there is room again.
+const maxRateLimitKeys = 10000
+
+type rateKey struct {
+	ival int64
+	str  string
+}
+
+type tokenBucket struct {
+	tokens     float64
+	last       int64 // monotime of the last refill
+	suppressed int
+	pending    bool // a summary is scheduled
+}
+
+type rateLimiter struct {
+	parent *Logger // emits summaries, bypassing this limiter
+	rate   float64 // tokens per second
+	burst  float64
+	key    string // field key to limit by; empty limits the logger as a whole
+	refill int64  // nanoseconds for an empty bucket to refill; 0 if it never does
+
+	mu        sync.Mutex
+	buckets   map[rateKey]*tokenBucket
+	overflow  tokenBucket
+	nextSweep int64 // monotime before which a full map isn't swept again
+}
+
+// RateLimited returns a child logger that lets through at most rate entries
+// per second on average, with bursts of up to burst. When entries have been
+// suppressed, a Warn summary with the count is logged through l once the
+// limiter lets entries through again. A rate of zero or less never refills:
+// only the first burst entries get through, and no summary is logged. Fatal
+// entries are never limited.
+func (l *Logger) RateLimited(rate float64, burst int) *Logger {
+	return l.RateLimitedBy("", rate, burst)
+}
+
+// RateLimitedBy is like RateLimited but keeps a separate bucket for each
+// value of the field named key, so one noisy tenant can't starve the others.
+// Entries without the field share a bucket.
+func (l *Logger) RateLimitedBy(key string, rate float64, burst int) *Logger {
+	if burst < 1 {
+		burst = 1
+	}
+	if !(rate > 0) {
+		rate = 0
+	}
+	r := &rateLimiter{
+		parent:   l,
+		rate:     rate,
+		burst:    float64(burst),
+		key:      key,
+		buckets:  make(map[rateKey]*tokenBucket),
+		overflow: tokenBucket{tokens: float64(burst)},
+	}
+	if rate > 0 {
+		r.refill = int64(float64(burst) / rate * float64(time.Second))
+	}
+	child := *l
+	child.limiter = r
+	return &child
+}
+
+func (r *rateLimiter) allow(level Level, fields []Field) bool {
+	if level >= FatalLevel {
+		return true
+	}
+	var k rateKey
+	var kf Field
+	if r.key != "" {
+		for i := range fields {
+			if fields[i].Key == r.key {
+				kf = fields[i]
+				k = rateKey{ival: kf.Ival, str: kf.Str}
+				if kf.Type == BytesType {
+					k.str = string(kf.Bval)
+				}
+				break
+			}
+		}
+	}
+
+	now := monotime()
+	r.mu.Lock()
+	b := r.bucket(k, now)
+	if r.rate > 0 {
+		b.tokens = min(r.burst, b.tokens+float64(now-b.last)/float64(time.Second)*r.rate)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		r.mu.Unlock()
+		return true
+	}
+
+	b.suppressed++
+	if !b.pending && r.rate > 0 {
+		b.pending = true
+		wait := time.Duration((1 - b.tokens) / r.rate * float64(time.Second))
+		if kf.Key != "" {
+			kf = cloneField(kf)
+		}
+		time.AfterFunc(wait, func() { r.summarize(b, kf) })
+	}
+	r.mu.Unlock()
+	return false
+}
+
+func (r *rateLimiter) bucket(k rateKey, now int64) *tokenBucket {
+	if b, ok := r.buckets[k]; ok {
+		return b
+	}
+	if len(r.buckets) >= maxRateLimitKeys {
+		// Buckets only become sweepable after a whole refill period idle, so
+		// sweeping more often finds little and costs a pass over every key.
+		if r.refill == 0 || now < r.nextSweep {
+			return &r.overflow
+		}
+		r.sweep(now)
+		r.nextSweep = now + r.refill
+		if len(r.buckets) >= maxRateLimitKeys {
+			return &r.overflow
+		}
+	}
+	b := &tokenBucket{tokens: r.burst, last: now}
+	r.buckets[k] = b
+	return b
+}
+
+// sweep drops buckets that have refilled completely and have no summary
+// pending; they are indistinguishable from new ones.
+func (r *rateLimiter) sweep(now int64) {
+	for k, b := range r.buckets {
+		if !b.pending && now-b.last >= r.refill {
+			delete(r.buckets, k)
+		}
+	}
+}
+
+func (r *rateLimiter) summarize(b *tokenBucket, key Field) {
+	r.mu.Lock()
+	n := b.suppressed
+	b.suppressed = 0
+	b.pending = false
+	r.mu.Unlock()
+
+	if n == 0 || !r.parent.level.Enabled(WarnLevel) {
+		return
+	}
+	if key.Key != "" {
+		r.parent.log(WarnLevel, "rate limit exceeded", []Field{Int("suppressed", n), key})
+	} else {
+		r.parent.log(WarnLevel, "rate limit exceeded", []Field{Int("suppressed", n)})
+	}
+}
+
+// cloneField copies f's byte slice so it outlives the caller's buffer.
+func cloneField(f Field) Field {
+	if f.Bval != nil {
+		f.Bval = append([]byte(nil), f.Bval...)
+	}
+	return f
+}
@@ -0,0 +1,149 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that summaries logged from timers can share
+// with the test.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (w *lockedBuffer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *lockedBuffer) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}
+
+// waitFor polls until w contains want and returns its contents.
+func waitFor(t *testing.T, w *lockedBuffer, want string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := w.String()
+		if strings.Contains(got, want) {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s in %q", want, got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	var buf lockedBuffer
+	log := New(Config{Output: &buf}).RateLimited(50, 2)
+	for i := 0; i < 5; i++ {
+		log.Info("burst", Int("i", i))
+	}
+	if n := strings.Count(buf.String(), `"msg":"burst"`); n != 2 {
+		t.Fatalf("%d entries got through the burst", n)
+	}
+
+	got := waitFor(t, &buf, `"msg":"rate limit exceeded","suppressed":3}`)
+	if !strings.Contains(got, `{"level":"WARN"`) {
+		t.Fatalf("summary level: %q", got)
+	}
+
+	// The summary is only due once a token is back.
+	log.Info("after")
+	if !strings.Contains(buf.String(), `"msg":"after"`) {
+		t.Fatalf("refilled bucket dropped the entry: %q", buf.String())
+	}
+}
+
+func TestRateLimitedBy(t *testing.T) {
+	var buf lockedBuffer
+	log := New(Config{Output: &buf}).RateLimitedBy("user", 0, 1)
+	for i := 0; i < 2; i++ {
+		log.Info("a", String("user", "alice"))
+		log.Info("b", String("user", "bob"))
+		log.Info("n", Int("user", 7))
+		log.Info("none")
+	}
+	got := buf.String()
+	for _, msg := range []string{"a", "b", "n", "none"} {
+		if n := strings.Count(got, `"msg":"`+msg+`"`); n != 1 {
+			t.Errorf("%s logged %d times", msg, n)
+		}
+	}
+}
+
+func TestRateLimitedNonPositiveRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		var buf lockedBuffer
+		log := New(Config{Output: &buf}).RateLimited(rate, 2)
+		for i := 0; i < 5; i++ {
+			log.Info("x")
+		}
+		if n := strings.Count(buf.String(), "\n"); n != 2 {
+			t.Fatalf("rate %v: %d lines", rate, n)
+		}
+		if log.limiter.buckets[rateKey{}].pending {
+			t.Fatalf("rate %v: summary scheduled for a bucket that never refills", rate)
+		}
+	}
+}
+
+func TestRateLimitedByOverflow(t *testing.T) {
+	log := New(Config{Output: &lockedBuffer{}}).RateLimitedBy("k", 1, 1)
+	r := log.limiter
+	for i := 0; i < maxRateLimitKeys; i++ {
+		if !r.allow(InfoLevel, []Field{Int("k", i)}) {
+			t.Fatalf("key %d denied its first entry", i)
+		}
+	}
+
+	// Every bucket is fresh, so new keys share the overflow bucket.
+	if !r.allow(InfoLevel, []Field{Int("k", -1)}) || r.allow(InfoLevel, []Field{Int("k", -2)}) {
+		t.Fatal("overflow bucket should allow exactly one entry")
+	}
+	if len(r.buckets) != maxRateLimitKeys {
+		t.Fatalf("%d buckets", len(r.buckets))
+	}
+
+	// The buckets have refilled, but the map was swept less than a refill
+	// period ago, so new keys still share the overflow bucket.
+	r.mu.Lock()
+	for _, b := range r.buckets {
+		b.last -= int64(2 * time.Second)
+	}
+	r.mu.Unlock()
+	if r.allow(InfoLevel, []Field{Int("k", -3)}) {
+		t.Fatal("map swept again within a refill period")
+	}
+
+	// Once the period has passed, a sweep makes room again.
+	r.mu.Lock()
+	r.nextSweep -= int64(2 * time.Second)
+	r.mu.Unlock()
+	if !r.allow(InfoLevel, []Field{Int("k", -4)}) {
+		t.Fatal("new key denied after sweep")
+	}
+	if len(r.buckets) != 1 {
+		t.Fatalf("%d buckets after sweep", len(r.buckets))
+	}
+}
+
+func TestRateLimitedFatal(t *testing.T) {
+	r := New(Config{Output: &lockedBuffer{}}).RateLimited(0, 1).limiter
+	if !r.allow(ErrorLevel, nil) || r.allow(ErrorLevel, nil) {
+		t.Fatal("bucket of one should allow exactly one entry")
+	}
+	if !r.allow(FatalLevel, nil) {
+		t.Fatal("fatal entry limited")
+	}
+}
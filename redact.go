@@ -0,0 +1,290 @@
+package mach
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type RedactAction uint8
+
+const (
+	// RedactMask replaces the value with RedactionPolicy.Mask.
+	RedactMask RedactAction = iota
+	// RedactDrop removes the field.
+	RedactDrop
+	// RedactPartial keeps the last four characters of values longer than
+	// eight and replaces the rest with '*'. Shorter values are masked.
+	RedactPartial
+	// RedactHMAC replaces the value with "hmac:" and the first 16 hex digits
+	// of its HMAC-SHA256 under RedactionPolicy.HMACKey, so equal values can
+	// still be correlated.
+	RedactHMAC
+)
+
+// KeyRule redacts fields whose key matches Pattern, compared
+// case-insensitively, where '*' matches any run of characters: "password",
+// "*token*", "x-api-*".
+type KeyRule struct {
+	Pattern string
+	Action  RedactAction
+}
+
+// ValueRule redacts every match of Pattern inside string, error and byte
+// fields, or drops the field if Action is RedactDrop. Valid, if set, can
+// reject false positives.
+type ValueRule struct {
+	Pattern *regexp.Regexp
+	Action  RedactAction
+	Valid   func(match string) bool
+}
+
+var (
+	creditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	jwtPattern        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// CreditCards matches card numbers of 13 to 19 digits, optionally grouped
+// with spaces or dashes, that pass the Luhn check.
+func CreditCards(action RedactAction) ValueRule {
+	return ValueRule{Pattern: creditCardPattern, Action: action, Valid: luhnValid}
+}
+
+func Emails(action RedactAction) ValueRule {
+	return ValueRule{Pattern: emailPattern, Action: action}
+}
+
+// JWTs matches JSON Web Tokens by their base64url-encoded "{" prefix.
+func JWTs(action RedactAction) ValueRule {
+	return ValueRule{Pattern: jwtPattern, Action: action}
+}
+
+// RedactionPolicy describes what must not reach the logs. Key rules are
+// checked first; the first matching one wins.
+type RedactionPolicy struct {
+	Keys    []KeyRule
+	Values  []ValueRule
+	HMACKey []byte // required for RedactHMAC, which otherwise masks
+	Mask    string // default "[REDACTED]"
+}
+
+type redactor struct {
+	keys    []KeyRule // patterns lower-cased
+	values  []ValueRule
+	hmacKey []byte
+	mask    string
+	hmacs   sync.Pool
+}
+
+func newRedactor(p *RedactionPolicy) *redactor {
+	r := &redactor{values: p.Values, hmacKey: p.HMACKey, mask: p.Mask}
+	if r.mask == "" {
+		r.mask = "[REDACTED]"
+	}
+	for _, k := range p.Keys {
+		r.keys = append(r.keys, KeyRule{Pattern: strings.ToLower(k.Pattern), Action: k.Action})
+	}
+	r.hmacs.New = func() any { return hmac.New(sha256.New, r.hmacKey) }
+	return r
+}
+
+// WithRedaction returns a child logger that applies p to every field before
+// encoding. It replaces any policy l already has. With fields l carries are
+// re-encoded under p only if they were added once l's ancestors had hooks or
+// a redaction policy; before that With keeps just their encoded form, which
+// is left as it is. To cover every With field, set Config.Redaction or call
+// WithRedaction before With.
+func (l *Logger) WithRedaction(p RedactionPolicy) *Logger {
+	child := *l
+	child.redactor = newRedactor(&p)
+	child.fields = child.redactor.redact(nil, l.fields)
+	if l.contextOnly {
+		return &child
+	}
+	child.context = make([][]byte, len(l.groups))
+	for i := range l.groups {
+		child.context[i] = l.groups[i].appendContext(nil, child.fields)
+	}
+	return &child
+}
+
+// redactPooled returns a pooled, redacted copy of fields. Release it with
+// releaseFields.
+func (r *redactor) redactPooled(fields []Field) *[]Field {
+	p := fieldSlicePool.Get().(*[]Field)
+	*p = r.redact((*p)[:0], fields)
+	return p
+}
+
+func releaseFields(p *[]Field) {
+	clear(*p)
+	fieldSlicePool.Put(p)
+}
+
+// redact appends the redacted fields to dst.
+func (r *redactor) redact(dst []Field, fields []Field) []Field {
+	for _, f := range fields {
+		if f, ok := r.redactField(f); ok {
+			dst = append(dst, f)
+		}
+	}
+	return dst
+}
+
+func (r *redactor) redactField(f Field) (Field, bool) {
+	for i := range r.keys {
+		if globMatchFold(r.keys[i].Pattern, f.Key) {
+			return r.applyKey(f, r.keys[i].Action)
+		}
+	}
+	if len(r.values) == 0 {
+		return f, true
+	}
+
+	var s string
+	switch f.Type {
+	case StringType, ErrorType:
+		s = f.Str
+	case BytesType:
+		s = string(f.Bval)
+	default:
+		return f, true
+	}
+	orig := s
+	for i := range r.values {
+		rule := &r.values[i]
+		locs := rule.Pattern.FindAllStringIndex(s, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range locs {
+			m := s[loc[0]:loc[1]]
+			if rule.Valid != nil && !rule.Valid(m) {
+				continue
+			}
+			if rule.Action == RedactDrop {
+				return Field{}, false
+			}
+			b.WriteString(s[last:loc[0]])
+			b.WriteString(r.replace(m, rule.Action))
+			last = loc[1]
+		}
+		if last > 0 {
+			b.WriteString(s[last:])
+			s = b.String()
+		}
+	}
+	if s == orig {
+		return f, true
+	}
+	if f.Type == BytesType {
+		return String(f.Key, s), true
+	}
+	f.Str = s
+	return f, true
+}
+
+func (r *redactor) applyKey(f Field, action RedactAction) (Field, bool) {
+	if action == RedactDrop {
+		return Field{}, false
+	}
+	var s string
+	switch f.Type {
+	case StringType, ErrorType:
+		s = f.Str
+	case BytesType:
+		s = string(f.Bval)
+	default:
+		// Numbers, times and the like are masked whatever the action.
+		return String(f.Key, r.mask), true
+	}
+	typ := f.Type
+	if typ == BytesType {
+		typ = StringType
+	}
+	return Field{Key: f.Key, Type: typ, Str: r.replace(s, action)}, true
+}
+
+func (r *redactor) replace(s string, action RedactAction) string {
+	switch action {
+	case RedactPartial:
+		if len(s) > 8 {
+			return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+		}
+	case RedactHMAC:
+		if len(r.hmacKey) > 0 {
+			h := r.hmacs.Get().(hash.Hash)
+			h.Reset()
+			_, _ = h.Write([]byte(s))
+			var sum [sha256.Size]byte
+			tok := "hmac:" + hex.EncodeToString(h.Sum(sum[:0])[:8])
+			r.hmacs.Put(h)
+			return tok
+		}
+	}
+	return r.mask
+}
+
+// globMatchFold reports whether key matches the lower-case pattern, where
+// '*' matches any run of bytes and letters compare case-insensitively.
+func globMatchFold(pattern, key string) bool {
+	px, kx := 0, 0
+	starP, starK := -1, 0
+	for kx < len(key) {
+		if px < len(pattern) {
+			switch c := pattern[px]; {
+			case c == '*':
+				starP, starK = px, kx
+				px++
+				continue
+			case c == lowerASCII(key[kx]):
+				px++
+				kx++
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starK++
+		px, kx = starP+1, starK
+	}
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
+}
+
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,122 @@
+package mach
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	// The base policy has no rules, but with one in place With keeps its
+	// fields, so WithRedaction can re-encode them.
+	base := New(Config{Output: &buf, Redaction: &RedactionPolicy{}}).With(String("api_token", "abc"))
+	log := base.WithRedaction(RedactionPolicy{
+		Keys: []KeyRule{
+			{Pattern: "password", Action: RedactMask},
+			{Pattern: "*TOKEN*", Action: RedactHMAC},
+			{Pattern: "ssn", Action: RedactDrop},
+			{Pattern: "pin", Action: RedactPartial},
+		},
+		Values: []ValueRule{
+			CreditCards(RedactPartial),
+			Emails(RedactMask),
+			JWTs(RedactDrop),
+		},
+		HMACKey: []byte("k"),
+	}).With(String("Password", "hunter2"))
+
+	log.Info("login",
+		String("user", "bob"),
+		String("ssn", "123-45-6789"),
+		Int("pin", 1234),
+		String("note", "card 4111 1111 1111 1111 for bob@example.com"),
+		String("order", "1234567890123"), // fails the Luhn check
+		Err(errors.New("sent to alice@example.org")),
+		Bytes("auth", []byte("Bearer eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig")),
+		String("session_token", "abc"),
+	)
+
+	got := buf.String()
+	for _, want := range []string{
+		`"api_token":"hmac:`,
+		`"Password":"[REDACTED]"`,
+		`"user":"bob"`,
+		`"pin":"[REDACTED]"`,
+		`"note":"card ***************1111 for [REDACTED]"`,
+		`"order":"1234567890123"`,
+		`"error":"sent to [REDACTED]"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in %s", want, got)
+		}
+	}
+	for _, leak := range []string{"hunter2", "123-45-6789", "bob@example.com", "eyJ", `"auth"`, `"ssn"`} {
+		if strings.Contains(got, leak) {
+			t.Errorf("%q leaked: %s", leak, got)
+		}
+	}
+
+	// Equal values tokenize equally, so they can still be correlated.
+	i := strings.Index(got, `"api_token":"`) + len(`"api_token":"`)
+	j := strings.Index(got, `"session_token":"`) + len(`"session_token":"`)
+	if got[i:i+21] != got[j:j+21] {
+		t.Errorf("HMAC tokens differ: %s vs %s", got[i:i+21], got[j:j+21])
+	}
+}
+
+func TestRedactionAfterPreHooks(t *testing.T) {
+	var buf bytes.Buffer
+	var seen []Field
+	log := New(Config{Output: &buf, Redaction: &RedactionPolicy{
+		Keys: []KeyRule{{Pattern: "password"}},
+	}}).
+		WithPreHook(func(e *HookEntry) bool {
+			e.Fields = append(e.Fields, String("password", "hunter2"))
+			return true
+		}).
+		WithPostHook(func(e *HookEntry) {
+			seen = append(seen[:0], e.Fields...)
+		})
+
+	log.Info("login", String("user", "bob"))
+	if got := buf.String(); strings.Contains(got, "hunter2") ||
+		!strings.HasSuffix(got, `"user":"bob","password":"[REDACTED]"}`+"\n") {
+		t.Fatalf("got %s", got)
+	}
+	if len(seen) != 2 || seen[1].Str != "[REDACTED]" {
+		t.Fatalf("post hook saw %v", seen)
+	}
+}
+
+func TestGlobMatchFold(t *testing.T) {
+	for _, c := range []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"password", "Password", true},
+		{"password", "password2", false},
+		{"*token*", "X-Auth-Token-Id", true},
+		{"*token", "tokens", false},
+		{"x-api-*", "X-API-KEY", true},
+		{"*", "", true},
+	} {
+		if got := globMatchFold(c.pattern, c.key); got != c.want {
+			t.Errorf("globMatchFold(%q, %q) = %v", c.pattern, c.key, got)
+		}
+	}
+}
+
+func TestRedactionAllocs(t *testing.T) {
+	log := New(Config{Output: io.Discard, Redaction: &RedactionPolicy{
+		Keys: []KeyRule{{Pattern: "*secret*"}},
+	}})
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("x", String("user", "bob"), Int("n", 1))
+	})
+	if allocs != 0 {
+		t.Fatalf("allocated %v times with no matches", allocs)
+	}
+}
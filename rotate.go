@@ -0,0 +1,365 @@
+package mach
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type RotateSchedule uint8
+
+const (
+	RotateNever RotateSchedule = iota
+	RotateHourly
+	RotateDaily
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type RotateConfig struct {
+	Filename   string
+	MaxSize    int64          // bytes; 0 disables size-based rotation
+	Schedule   RotateSchedule // RotateNever disables time-based rotation
+	MaxBackups int            // 0 keeps all backups
+	MaxAge     time.Duration  // 0 keeps backups regardless of age
+	Compress   bool           // gzip rotated files in the background
+}
+
+// RotatingFile is an io.Writer that appends to a file and rotates it by size
+// or on a schedule. It is safe for concurrent use.
+type RotatingFile struct {
+	cfg RotateConfig
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	next time.Time
+
+	mill     chan struct{}
+	millDone chan struct{}
+	closed   bool
+}
+
+func NewRotatingFile(cfg RotateConfig) (*RotatingFile, error) {
+	if cfg.Filename == "" {
+		return nil, errors.New("mach: RotateConfig.Filename is required")
+	}
+	r := &RotatingFile{
+		cfg:      cfg,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Filename), 0o755); err != nil {
+		return nil, err
+	}
+	if err := r.open(time.Now()); err != nil {
+		return nil, err
+	}
+	go r.runMill()
+	r.kickMill()
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	now := time.Now()
+	if r.file == nil {
+		if err := r.open(now); err != nil {
+			return 0, err
+		}
+	}
+	if (r.cfg.Schedule != RotateNever && !now.Before(r.next)) ||
+		(r.cfg.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.cfg.MaxSize) {
+		if err := r.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside as a backup and starts a
+// new one.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+	return r.rotate(time.Now())
+}
+
+// Reopen closes and reopens the file at the configured path. Use it after an
+// external tool such as logrotate has moved the file away.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file != nil {
+		_ = r.file.Close()
+		r.file = nil
+	}
+	return r.open(time.Now())
+}
+
+// ReopenOnSignal calls Reopen whenever one of sigs is received, SIGHUP if
+// none are given. The returned function stops listening.
+func (r *RotatingFile) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				_ = r.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Close closes the file and waits for any pending compression to finish.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	close(r.mill)
+	<-r.millDone
+	return err
+}
+
+func (r *RotatingFile) open(now time.Time) error {
+	f, err := os.OpenFile(r.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	r.next = nextRotation(r.cfg.Schedule, now)
+	return nil
+}
+
+func (r *RotatingFile) rotate(now time.Time) error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+	if err := os.Rename(r.cfg.Filename, r.backupName(now)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(now); err != nil {
+		return err
+	}
+	r.kickMill()
+	return nil
+}
+
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			if _, err := os.Stat(name + ".gz"); os.IsNotExist(err) {
+				return name
+			}
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.cfg.Filename)
+	base := filepath.Base(r.cfg.Filename)
+	ext = filepath.Ext(base)
+	return dir, base[:len(base)-len(ext)] + "-", ext
+}
+
+func nextRotation(s RotateSchedule, now time.Time) time.Time {
+	y, m, d := now.Date()
+	switch s {
+	case RotateHourly:
+		return time.Date(y, m, d, now.Hour()+1, 0, 0, 0, now.Location())
+	case RotateDaily:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
+
+func (r *RotatingFile) kickMill() {
+	if !r.cfg.Compress && r.cfg.MaxBackups == 0 && r.cfg.MaxAge == 0 {
+		return
+	}
+	select {
+	case r.mill <- struct{}{}:
+	default:
+	}
+}
+
+func (r *RotatingFile) runMill() {
+	defer close(r.millDone)
+	for range r.mill {
+		_ = r.millOnce()
+	}
+}
+
+type backupFile struct {
+	path string
+	ts   time.Time
+}
+
+func (r *RotatingFile) millOnce() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backupFile
+	if r.cfg.MaxBackups > 0 && len(backups) > r.cfg.MaxBackups {
+		remove = append(remove, backups[r.cfg.MaxBackups:]...)
+		backups = backups[:r.cfg.MaxBackups]
+	}
+	if r.cfg.MaxAge > 0 {
+		cutoff := time.Now().Add(-r.cfg.MaxAge)
+		keep := backups[:0]
+		for _, b := range backups {
+			if b.ts.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				keep = append(keep, b)
+			}
+		}
+		backups = keep
+	}
+
+	var firstErr error
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if r.cfg.Compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, ".gz") {
+				continue
+			}
+			if err := gzipFile(b.path); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// backups returns the rotated files for this writer, newest first.
+func (r *RotatingFile) backups() ([]backupFile, error) {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []backupFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(name[len(prefix):], ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		ts, err := time.Parse(backupTimeFormat, stamp[:len(stamp)-len(ext)])
+		if err != nil {
+			continue
+		}
+		out = append(out, backupFile{path: filepath.Join(dir, name), ts: ts})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ts.After(out[j].ts) })
+	return out, nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,166 @@
+package mach
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	r, err := NewRotatingFile(RotateConfig{Filename: name, MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := readDir(t, dir)
+	if len(names) != 3 || names[2] != "app.log" {
+		t.Fatalf("files %v", names)
+	}
+	for i, want := range []string{"aaaaaa\n", "bbbbbb\n"} {
+		if !strings.HasPrefix(names[i], "app-") || !strings.HasSuffix(names[i], ".log") {
+			t.Fatalf("backup name %q", names[i])
+		}
+		b, _ := os.ReadFile(filepath.Join(dir, names[i]))
+		if string(b) != want {
+			t.Errorf("%s = %q, want %q", names[i], b, want)
+		}
+	}
+	if b, _ := os.ReadFile(name); string(b) != "cccccc\n" {
+		t.Fatalf("current file %q", b)
+	}
+}
+
+func TestRotatingFileSchedule(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRotatingFile(RotateConfig{Filename: filepath.Join(dir, "app.log"), Schedule: RotateHourly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if now := time.Now(); !r.next.After(now) || r.next.Sub(now) > time.Hour {
+		t.Fatalf("next rotation %v", r.next)
+	}
+	_, _ = r.Write([]byte("first\n"))
+	r.mu.Lock()
+	r.next = time.Now().Add(-time.Second) // the hour has passed
+	r.mu.Unlock()
+	_, _ = r.Write([]byte("second\n"))
+
+	if names := readDir(t, dir); len(names) != 2 {
+		t.Fatalf("files %v", names)
+	}
+	if got := nextRotation(RotateDaily, time.Date(2024, 3, 31, 23, 59, 0, 0, time.UTC)); !got.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("daily rotation at %v", got)
+	}
+}
+
+func TestRotatingFilePruneAndCompress(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	backup := func(age time.Duration) string {
+		p := filepath.Join(dir, "app-"+now.Add(-age).Format(backupTimeFormat)+".log")
+		if err := os.WriteFile(p, []byte("old\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Base(p)
+	}
+	newest := backup(time.Minute)
+	second := backup(2 * time.Minute)
+	backup(3 * time.Minute) // beyond MaxBackups
+	backup(48 * time.Hour)  // beyond MaxAge too
+	_ = os.WriteFile(filepath.Join(dir, "other.log"), nil, 0o644)
+
+	r, err := NewRotatingFile(RotateConfig{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxBackups: 2,
+		MaxAge:     24 * time.Hour,
+		Compress:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil { // waits for the mill
+		t.Fatal(err)
+	}
+
+	names := readDir(t, dir)
+	want := []string{"app.log", newest + ".gz", second + ".gz", "other.log"}
+	sort.Strings(want)
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("files %v, want %v", names, want)
+	}
+
+	f, err := os.Open(filepath.Join(dir, newest+".gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != "old\n" {
+		t.Fatalf("decompressed %q", b)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	r, err := NewRotatingFile(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, _ = r.Write([]byte("before\n"))
+	// What logrotate does before signalling the process.
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Write([]byte("after\n"))
+
+	if b, _ := os.ReadFile(name + ".1"); string(b) != "before\n" {
+		t.Fatalf("moved file %q", b)
+	}
+	if b, _ := os.ReadFile(name); string(b) != "after\n" {
+		t.Fatalf("reopened file %q", b)
+	}
+
+	_ = r.Close()
+	if _, err := r.Write([]byte("x")); err != os.ErrClosed {
+		t.Fatalf("write after close: %v", err)
+	}
+}
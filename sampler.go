@@ -0,0 +1,140 @@
+package mach
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+)
+
+type SamplingDecision uint8
+
+const (
+	LogSampled SamplingDecision = iota
+	LogDropped
+)
+
+// SamplingConfig logs the first First entries with a given level and message
+// in each Tick, then every Thereafter-th one. Thereafter 0 drops the rest.
+// Fatal entries are never sampled.
+//
+// A dropped entry costs a message hash and a few atomic operations on top of
+// a disabled level check. Ticks are counted by a timer, which only runs while
+// entries arrive, rather than by reading the clock for every entry.
+type SamplingConfig struct {
+	Tick       time.Duration // default 1s
+	First      int
+	Thereafter int
+	// Hook, if set, is called with every sampling decision.
+	Hook func(ent Entry, dec SamplingDecision)
+}
+
+// samplerBuckets is the number of counters per level. Messages are hashed
+// into buckets, so distinct messages can occasionally share a counter.
+const samplerBuckets = 4096
+
+var samplerSeed = maphash.MakeSeed()
+
+type sampleCounter struct {
+	tick atomic.Uint64 // the sampler tick n counts entries in
+	n    atomic.Uint64
+}
+
+type sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	hook       func(Entry, SamplingDecision)
+	counts     [len(levelNames)][samplerBuckets]sampleCounter
+
+	// now is the current tick, advanced by timer. The timer stops after a
+	// tick without entries and the next entry restarts it, so idle samplers
+	// don't wake up.
+	now     atomic.Uint64
+	active  atomic.Bool // entries arrived during this tick
+	running atomic.Bool // timer is armed
+	timer   *time.Timer
+}
+
+func newSampler(cfg *SamplingConfig) *sampler {
+	tick := cfg.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	s := &sampler{
+		tick:       tick,
+		first:      uint64(max(cfg.First, 0)),
+		thereafter: uint64(max(cfg.Thereafter, 0)),
+		hook:       cfg.Hook,
+	}
+	s.timer = time.AfterFunc(tick, s.advance)
+	s.timer.Stop()
+	return s
+}
+
+// monoEpoch anchors a monotonic clock for rate limiting and deduplication,
+// which is cheaper to read than the wall clock.
+var monoEpoch = time.Now()
+
+func monotime() int64 {
+	return int64(time.Since(monoEpoch))
+}
+
+func (s *sampler) allow(level Level, msg string) bool {
+	if level >= FatalLevel {
+		return true
+	}
+	idx := int(level - TraceLevel)
+	if idx < 0 {
+		idx = 0
+	}
+
+	if !s.active.Load() {
+		s.wake()
+	}
+	c := &s.counts[idx][maphash.String(samplerSeed, msg)%samplerBuckets]
+	n := c.incCheckReset(s.now.Load())
+	dec := LogDropped
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		dec = LogSampled
+	}
+	if s.hook != nil {
+		s.hook(Entry{Level: level, Time: time.Now(), Message: msg}, dec)
+	}
+	return dec == LogSampled
+}
+
+// wake marks the sampler active and restarts the timer if it had stopped.
+// Time has passed since then, so the restart also begins a new tick.
+func (s *sampler) wake() {
+	s.active.Store(true)
+	if s.running.CompareAndSwap(false, true) {
+		s.now.Add(1)
+		s.timer.Reset(s.tick)
+	}
+}
+
+func (s *sampler) advance() {
+	s.now.Add(1)
+	if s.active.Swap(false) {
+		s.timer.Reset(s.tick)
+		return
+	}
+	s.running.Store(false)
+	// An entry that set active before running was cleared didn't restart
+	// the timer, so do it for it.
+	if s.active.Load() && s.running.CompareAndSwap(false, true) {
+		s.timer.Reset(s.tick)
+	}
+}
+
+func (c *sampleCounter) incCheckReset(now uint64) uint64 {
+	tick := c.tick.Load()
+	if tick == now {
+		return c.n.Add(1)
+	}
+	c.n.Store(1)
+	if !c.tick.CompareAndSwap(tick, now) {
+		return c.n.Add(1)
+	}
+	return 1
+}
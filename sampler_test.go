@@ -0,0 +1,71 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	var sampled, dropped int
+	log := New(Config{Output: &buf, Level: DebugLevel, Sampling: &SamplingConfig{
+		Tick:       time.Hour,
+		First:      2,
+		Thereafter: 3,
+		Hook: func(ent Entry, dec SamplingDecision) {
+			if ent.Message != "hot" {
+				t.Errorf("hook got %q", ent.Message)
+			}
+			if dec == LogSampled {
+				sampled++
+			} else {
+				dropped++
+			}
+		},
+	}})
+
+	// Entries 1, 2, 5 and 8 of 9 pass.
+	for i := 1; i <= 9; i++ {
+		log.Info("hot", Int("i", i))
+	}
+	got := buf.String()
+	for _, want := range []string{`"i":1}`, `"i":2}`, `"i":5}`, `"i":8}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if n := strings.Count(got, "\n"); n != 4 || sampled != 4 || dropped != 5 {
+		t.Fatalf("lines %d, sampled %d, dropped %d", n, sampled, dropped)
+	}
+
+	// Counters are per level and message.
+	buf.Reset()
+	log.Warn("hot")
+	if buf.Len() == 0 {
+		t.Fatal("Warn shared Info's counter")
+	}
+
+	// Once the tick is over the count starts again.
+	log.sampler.now.Add(1)
+	buf.Reset()
+	log.Info("hot", Int("i", 10))
+	log.Info("hot", Int("i", 11))
+	log.Info("hot", Int("i", 12))
+	if got := buf.String(); strings.Count(got, "\n") != 2 || strings.Contains(got, `"i":12`) {
+		t.Fatalf("after reset: %q", got)
+	}
+}
+
+func TestSamplingThereafterZero(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf, Sampling: &SamplingConfig{First: 1}})
+	for i := 0; i < 5; i++ {
+		log.Info("x")
+		log.Error("x")
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("got %d lines: %q", n, buf.String())
+	}
+}
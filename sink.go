@@ -0,0 +1,82 @@
+package mach
+
+import (
+	"io"
+	"math"
+)
+
+// minLevel lets a sink accept every entry the logger's own level allows.
+const minLevel Level = math.MinInt32
+
+// Sink is one destination for log entries. Entries below Level are not
+// written to it; Encoder defaults to JSONEncoder. Filter, if set, limits the
+// fields it receives.
+//
+// The zero Level is InfoLevel, so a sink that should receive Debug or Trace
+// entries must set Level explicitly.
+type Sink struct {
+	Output  io.Writer
+	Level   Level
+	Encoder EntryEncoder
+	Filter  *FieldFilter
+}
+
+// sinkGroup holds the sinks that share an encoder and an equivalent filter,
+// so each entry is encoded at most once per distinct pair.
+type sinkGroup struct {
+	enc    EntryEncoder
+	filter *fieldFilter
+	level  Level // lowest level of any sink in the group
+	sinks  []Sink
+}
+
+// encode encodes an entry for the group, leaving out the fields its filter
+// rejects.
+func (g *sinkGroup) encode(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	if g.filter == nil {
+		return encodeEntry(dst, g.enc, ent, context, fields)
+	}
+	p := fieldSlicePool.Get().(*[]Field)
+	*p = g.filter.apply((*p)[:0], fields)
+	dst = encodeEntry(dst, g.enc, ent, context, *p)
+	releaseFields(p)
+	return dst
+}
+
+// appendContext pre-encodes With fields for the group.
+func (g *sinkGroup) appendContext(dst []byte, fields []Field) []byte {
+	if g.filter == nil {
+		return encodeContext(dst, g.enc, fields)
+	}
+	p := fieldSlicePool.Get().(*[]Field)
+	*p = g.filter.apply((*p)[:0], fields)
+	dst = encodeContext(dst, g.enc, *p)
+	releaseFields(p)
+	return dst
+}
+
+func newSinkGroups(sinks []Sink) []sinkGroup {
+	var groups []sinkGroup
+	for _, s := range sinks {
+		if s.Output == nil {
+			continue
+		}
+		if s.Encoder == nil {
+			s.Encoder = jsonEnc
+		}
+		filter := newFieldFilter(s.Filter)
+		i := 0
+		for i < len(groups) && (groups[i].enc != s.Encoder || !groups[i].filter.equal(filter)) {
+			i++
+		}
+		if i == len(groups) {
+			groups = append(groups, sinkGroup{enc: s.Encoder, filter: filter, level: s.Level})
+		}
+		g := &groups[i]
+		if s.Level < g.level {
+			g.level = s.Level
+		}
+		g.sinks = append(g.sinks, s)
+	}
+	return groups
+}
@@ -0,0 +1,76 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// countingEncoder counts the entries it encodes.
+type countingEncoder struct {
+	n int
+}
+
+func (e *countingEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	return appendJSONContext(dst, fields)
+}
+
+func (e *countingEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	e.n++
+	return appendJSONEntry(dst, ent, context, fields)
+}
+
+func TestSinkLevels(t *testing.T) {
+	var debug, info, errs, def bytes.Buffer
+	log := New(Config{Level: DebugLevel, Sinks: []Sink{
+		{Output: &debug, Level: DebugLevel, Encoder: ConsoleEncoder()},
+		{Output: &info, Level: InfoLevel},
+		{Output: &errs, Level: ErrorLevel},
+		{Output: &def}, // zero Level is InfoLevel
+	}})
+	log.Trace("t")
+	log.Debug("d")
+	log.Info("i")
+	log.Error("e")
+
+	count := func(b *bytes.Buffer) int { return strings.Count(b.String(), "\n") }
+	if n := count(&debug); n != 3 || !strings.Contains(debug.String(), "DEBUG") {
+		t.Errorf("debug sink got %d lines: %q", n, debug.String())
+	}
+	if n := count(&info); n != 2 {
+		t.Errorf("info sink got %d lines: %q", n, info.String())
+	}
+	if n := count(&errs); n != 1 || !strings.Contains(errs.String(), `"msg":"e"`) {
+		t.Errorf("error sink got %d lines: %q", n, errs.String())
+	}
+	if def.String() != info.String() {
+		t.Errorf("default sink %q, want %q", def.String(), info.String())
+	}
+}
+
+func TestSinkSharedEncoding(t *testing.T) {
+	enc := &countingEncoder{}
+	var a, b, c bytes.Buffer
+	log := New(Config{Level: DebugLevel, Sinks: []Sink{
+		{Output: &a, Level: DebugLevel, Encoder: enc},
+		{Output: &b, Level: InfoLevel, Encoder: enc},
+		{Output: &c, Level: InfoLevel},
+	}})
+	if len(log.groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(log.groups))
+	}
+
+	log.With(String("svc", "api")).With(Int("shard", 3)).Info("hello")
+	if enc.n != 1 {
+		t.Fatalf("encoded %d times for two sinks", enc.n)
+	}
+	if a.String() != b.String() || a.String() != c.String() ||
+		!strings.HasSuffix(a.String(), `"msg":"hello","svc":"api","shard":3}`+"\n") {
+		t.Fatalf("a=%q b=%q c=%q", a.String(), b.String(), c.String())
+	}
+
+	log.Debug("only a")
+	if enc.n != 2 || strings.Contains(b.String(), "only a") {
+		t.Fatalf("debug entry: n=%d b=%q", enc.n, b.String())
+	}
+}
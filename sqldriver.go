@@ -0,0 +1,325 @@
+package mach
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+type SQLConfig struct {
+	// Level is the level of ordinary queries. Slow queries log at Warn and
+	// failed ones at Error.
+	Level Level
+	// SlowThreshold, if positive, marks queries taking at least this long
+	// as slow.
+	SlowThreshold time.Duration
+	// LogArgs adds the query arguments as an "args" field.
+	LogArgs bool
+	// RedactArg, if set, is asked about each argument; when it returns true
+	// the value is logged as "xxxxx". ordinal starts at 1.
+	RedactArg func(query string, ordinal int, name string) bool
+}
+
+// WrapDriver returns a driver that logs every statement run through d's
+// connections with its query, duration, rows affected and error. Register it
+// under a new name with sql.Register.
+func WrapDriver(l *Logger, d driver.Driver, cfg SQLConfig) driver.Driver {
+	return &sqlDriver{d: d, log: &sqlLogger{l: l, cfg: cfg}}
+}
+
+// WrapConnector is WrapDriver for use with sql.OpenDB.
+func WrapConnector(l *Logger, c driver.Connector, cfg SQLConfig) driver.Connector {
+	return &sqlConnector{c: c, log: &sqlLogger{l: l, cfg: cfg}}
+}
+
+type sqlLogger struct {
+	l   *Logger
+	cfg SQLConfig
+}
+
+func (s *sqlLogger) log(ctx context.Context, msg, query string, args []driver.NamedValue, d time.Duration, res driver.Result, err error) {
+	level := s.cfg.Level
+	slow := s.cfg.SlowThreshold > 0 && d >= s.cfg.SlowThreshold
+	switch {
+	case err != nil:
+		level = ErrorLevel
+	case slow:
+		level = max(level, WarnLevel)
+	}
+	if !s.l.enabled(level) {
+		return
+	}
+
+	var arr [7]Field
+	fields := append(arr[:0], String("query", query))
+	if s.cfg.LogArgs && len(args) > 0 {
+		fields = append(fields, String("args", s.formatArgs(query, args)))
+	}
+	fields = append(fields, Duration("duration", d))
+	if res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			fields = append(fields, Int64("rows_affected", n))
+		}
+	}
+	if slow {
+		fields = append(fields, Bool("slow", true))
+	}
+	if err != nil {
+		fields = append(fields, Err(err))
+	}
+	s.l.logCtx(ctx, level, msg, fields)
+}
+
+func (s *sqlLogger) formatArgs(query string, args []driver.NamedValue) string {
+	b := []byte{'['}
+	for i, a := range args {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		if s.cfg.RedactArg != nil && s.cfg.RedactArg(query, a.Ordinal, a.Name) {
+			b = append(b, `"xxxxx"`...)
+			continue
+		}
+		switch v := a.Value.(type) {
+		case nil:
+			b = append(b, "NULL"...)
+		case int64:
+			b = strconv.AppendInt(b, v, 10)
+		case float64:
+			b = strconv.AppendFloat(b, v, 'g', -1, 64)
+		case bool:
+			b = strconv.AppendBool(b, v)
+		case string:
+			b = strconv.AppendQuote(b, v)
+		case []byte:
+			b = strconv.AppendQuote(b, string(v))
+		case time.Time:
+			b = v.AppendFormat(b, time.RFC3339Nano)
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(append(b, ']'))
+}
+
+type sqlDriver struct {
+	d   driver.Driver
+	log *sqlLogger
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{c: c, log: d.log}, nil
+}
+
+type sqlConnector struct {
+	c   driver.Connector
+	log *sqlLogger
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{c: conn, log: c.log}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return &sqlDriver{d: c.c.Driver(), log: c.log}
+}
+
+// Close closes the wrapped connector if it has a Close method, as sql.DB
+// does for connectors it owns.
+func (c *sqlConnector) Close() error {
+	if cl, ok := c.c.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// sqlConn implements the optional driver interfaces by delegating, and
+// returns driver.ErrSkip where the wrapped connection lacks one so that
+// database/sql takes its usual fallback path.
+type sqlConn struct {
+	c   driver.Conn
+	log *sqlLogger
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.c.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{s: s, query: query, log: c.log}, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.c.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("mach: driver does not support transaction options")
+	}
+	return c.c.Begin()
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.c.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	c.log.log(ctx, "sql exec", query, args, time.Since(start), res, err)
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.c.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	c.log.log(ctx, "sql query", query, args, time.Since(start), nil, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.c.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.c.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.c.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.c.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	s     driver.Stmt
+	query string
+	log   *sqlLogger
+}
+
+func (s *sqlStmt) Close() error {
+	return s.s.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := s.s.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = plainValues(args); err == nil {
+			res, err = s.s.Exec(vals)
+		}
+	}
+	s.log.log(ctx, "sql exec", s.query, args, time.Since(start), res, err)
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.s.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = plainValues(args); err == nil {
+			rows, err = s.s.Query(vals)
+		}
+	}
+	s.log.log(ctx, "sql query", s.query, args, time.Since(start), nil, err)
+	return rows, err
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.s.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+func plainValues(args []driver.NamedValue) ([]driver.Value, error) {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("mach: driver does not support named parameters")
+		}
+		vals[i] = a.Value
+	}
+	return vals, nil
+}
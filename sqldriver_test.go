@@ -0,0 +1,129 @@
+package mach
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDriver accepts any query. "slow" queries sleep, "fail" ones error, and
+// exec reports one affected row per argument. Its connection supports
+// QueryerContext but not ExecerContext, so both the direct and the prepared
+// statement paths get exercised.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := fakeRun(query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := fakeRun(s.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("unused")
+}
+
+func fakeRun(query string) error {
+	if strings.Contains(query, "slow") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if strings.Contains(query, "fail") {
+		return errors.New("syntax error")
+	}
+	return nil
+}
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"n"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func TestSQLDriver(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf, Level: DebugLevel})
+	sql.Register("mach-fake", WrapDriver(log, fakeDriver{}, SQLConfig{
+		Level:         DebugLevel,
+		SlowThreshold: 10 * time.Millisecond,
+		LogArgs:       true,
+		RedactArg: func(query string, ordinal int, name string) bool {
+			return ordinal == 2
+		},
+	}))
+	db, err := sql.Open("mach-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := WithFields(context.Background(), String("request_id", "r1"))
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = ? WHERE password = ?", "bob", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT slow").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("scan: %v %d", err, n)
+	}
+	if _, err := db.Exec("fail"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%v: %q", err, line)
+		}
+		entries = append(entries, m)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("want 3 entries, got %q", buf.String())
+	}
+
+	e := entries[0]
+	if e["level"] != "DEBUG" || e["msg"] != "sql exec" || e["args"] != `["bob", "xxxxx"]` ||
+		e["rows_affected"] != 2.0 || e["request_id"] != "r1" || e["duration"] == nil {
+		t.Fatalf("exec entry %v", e)
+	}
+	if e := entries[1]; e["level"] != "WARN" || e["msg"] != "sql query" || e["slow"] != true {
+		t.Fatalf("slow entry %v", e)
+	}
+	if e := entries[2]; e["level"] != "ERROR" || e["error"] != "syntax error" || e["query"] != "fail" {
+		t.Fatalf("error entry %v", e)
+	}
+}
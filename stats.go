@@ -0,0 +1,85 @@
+package mach
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errorReportInterval bounds how often internal failures are written to
+// ErrorOutput; failures in between are only counted.
+const errorReportInterval = time.Second
+
+// WriteStats is a snapshot of a logger's output counters. Counters are shared
+// between a logger and all loggers derived from it.
+type WriteStats struct {
+	BytesWritten uint64
+	FailedWrites uint64
+	ShortWrites  uint64
+}
+
+type writeStats struct {
+	bytes  atomic.Uint64
+	failed atomic.Uint64
+	short  atomic.Uint64
+
+	errOut     io.Writer
+	mu         sync.Mutex
+	lastReport time.Time
+	suppressed int
+}
+
+func newWriteStats(errOut io.Writer) *writeStats {
+	return &writeStats{errOut: errOut}
+}
+
+func (s *writeStats) record(n, want int, err error) {
+	s.bytes.Add(uint64(n))
+	switch {
+	case err != nil:
+		s.failed.Add(1)
+		s.report("write error", err)
+	case n < want:
+		s.short.Add(1)
+		s.report("write error", io.ErrShortWrite)
+	}
+}
+
+// report writes a rate-limited line describing an internal failure.
+func (s *writeStats) report(what string, err error) {
+	if s.errOut == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastReport) < errorReportInterval {
+		s.suppressed++
+		return
+	}
+	b := now.AppendFormat(nil, time.RFC3339Nano)
+	b = append(b, " mach: "...)
+	b = append(b, what...)
+	b = append(b, ": "...)
+	b = append(b, err.Error()...)
+	if s.suppressed > 0 {
+		b = append(b, " ("...)
+		b = appendInt64(b, int64(s.suppressed))
+		b = append(b, " more suppressed)"...)
+	}
+	b = append(b, '\n')
+	_, _ = s.errOut.Write(b)
+	s.lastReport = now
+	s.suppressed = 0
+}
+
+// WriteStats returns the output counters shared by l and its children.
+func (l *Logger) WriteStats() WriteStats {
+	return WriteStats{
+		BytesWritten: l.stats.bytes.Load(),
+		FailedWrites: l.stats.failed.Load(),
+		ShortWrites:  l.stats.short.Load(),
+	}
+}
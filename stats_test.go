@@ -0,0 +1,68 @@
+package mach
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails or truncates writes on demand.
+type flakyWriter struct {
+	err   error
+	short bool
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	switch {
+	case w.err != nil:
+		return 0, w.err
+	case w.short:
+		return len(p) / 2, nil
+	}
+	return len(p), nil
+}
+
+func TestWriteStats(t *testing.T) {
+	w := &flakyWriter{}
+	var errOut bytes.Buffer
+	log := New(Config{Output: w, ErrorOutput: &errOut})
+	child := log.With(String("k", "v"))
+
+	log.Info("ok")
+	n := log.WriteStats().BytesWritten
+	if n == 0 {
+		t.Fatal("no bytes counted")
+	}
+
+	w.short = true
+	child.Info("short")
+	w.short = false
+	w.err = errors.New("disk full")
+	log.Info("fail")
+	log.Info("fail")
+	child.Info("fail")
+
+	st := log.WriteStats()
+	if st.FailedWrites != 3 || st.ShortWrites != 1 || st.BytesWritten <= n {
+		t.Fatalf("stats %+v", st)
+	}
+	if child.WriteStats() != st {
+		t.Fatalf("child stats %+v differ from %+v", child.WriteStats(), st)
+	}
+
+	// Only the first failure within the interval is reported.
+	if got := errOut.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "mach: write error: short write") {
+		t.Fatalf("error output %q", got)
+	}
+
+	log.stats.mu.Lock()
+	log.stats.lastReport = time.Now().Add(-errorReportInterval)
+	log.stats.mu.Unlock()
+	log.Info("fail")
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "mach: write error: disk full (3 more suppressed)") {
+		t.Fatalf("error output %q", errOut.String())
+	}
+}
@@ -0,0 +1,92 @@
+package mach
+
+import (
+	"bytes"
+	"log"
+	"sync"
+)
+
+// maxLineSize bounds the partial line a LineWriter holds; longer lines are
+// split.
+const maxLineSize = 64 << 10
+
+// LineWriter is an io.Writer that logs each line written to it as the msg of
+// an entry at a fixed level, for libraries that only accept a writer. A
+// trailing partial line is held until it is completed or Flush is called.
+type LineWriter struct {
+	log   *Logger
+	level Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func NewLineWriter(l *Logger, level Level) *LineWriter {
+	return &LineWriter{log: l, level: level}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			for len(w.buf) >= maxLineSize {
+				w.logLine(w.buf[:maxLineSize])
+				w.buf = append(w.buf[:0], w.buf[maxLineSize:]...)
+			}
+			break
+		}
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, p[:i]...)
+			w.logLine(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.logLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush logs a pending partial line.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+func (w *LineWriter) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 || !w.log.enabled(w.level) {
+		return
+	}
+	w.log.log(w.level, string(line), nil)
+}
+
+// NewStdLog returns a *log.Logger whose output is logged through l at level.
+func NewStdLog(l *Logger, level Level) *log.Logger {
+	return log.New(NewLineWriter(l, level), "", 0)
+}
+
+// RedirectStdLog sends the standard library's global logger through l at
+// level and returns a function restoring its previous output, prefix and
+// flags. The timestamp and prefix are dropped, since entries carry their
+// own time.
+func RedirectStdLog(l *Logger, level Level) func() {
+	flags, prefix, out := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(NewLineWriter(l, level))
+	return func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(out)
+	}
+}
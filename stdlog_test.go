@@ -0,0 +1,45 @@
+package mach
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewLineWriter(New(Config{Output: &buf}), WarnLevel)
+
+	_, _ = w.Write([]byte("first\r\nsec"))
+	_, _ = w.Write([]byte("ond\n\nthi"))
+	if strings.Contains(buf.String(), "thi") {
+		t.Fatalf("partial line logged early: %q", buf.String())
+	}
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{`"msg":"first"`, `"msg":"second"`, `"msg":"thi"`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %q", buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, `"level":"WARN"`) || !strings.HasSuffix(line, want[i]+"}") {
+			t.Fatalf("line %d: %q", i, line)
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	restore := RedirectStdLog(New(Config{Output: &buf}), InfoLevel)
+	log.Printf("from %s", "stdlib")
+	restore()
+
+	if !strings.HasSuffix(buf.String(), `"msg":"from stdlib"}`+"\n") {
+		t.Fatalf("got %q", buf.String())
+	}
+	if log.Flags() != log.LstdFlags {
+		t.Fatalf("flags not restored: %d", log.Flags())
+	}
+}
@@ -0,0 +1,122 @@
+package mach
+
+import (
+	"fmt"
+	"os"
+)
+
+// SugaredLogger trades a little speed for a printf-style and loosely typed
+// key-value API. Arguments are only formatted or converted when the level is
+// enabled.
+type SugaredLogger struct {
+	l *Logger
+}
+
+// Sugar wraps l in a SugaredLogger.
+func (l *Logger) Sugar() *SugaredLogger {
+	return &SugaredLogger{l: l}
+}
+
+// Desugar returns the underlying Logger.
+func (s *SugaredLogger) Desugar() *Logger {
+	return s.l
+}
+
+// With is like Logger.With, taking alternating keys and values.
+func (s *SugaredLogger) With(keysAndValues ...any) *SugaredLogger {
+	var arr [16]Field
+	return &SugaredLogger{l: s.l.With(sweeten(arr[:0], keysAndValues)...)}
+}
+
+func (s *SugaredLogger) Debugf(template string, args ...any) { s.logf(DebugLevel, template, args) }
+func (s *SugaredLogger) Infof(template string, args ...any)  { s.logf(InfoLevel, template, args) }
+func (s *SugaredLogger) Warnf(template string, args ...any)  { s.logf(WarnLevel, template, args) }
+func (s *SugaredLogger) Errorf(template string, args ...any) { s.logf(ErrorLevel, template, args) }
+
+func (s *SugaredLogger) Fatalf(template string, args ...any) {
+	s.logf(FatalLevel, template, args)
+	os.Exit(1)
+}
+
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...any) {
+	s.logw(DebugLevel, msg, keysAndValues)
+}
+
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...any) {
+	s.logw(InfoLevel, msg, keysAndValues)
+}
+
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...any) {
+	s.logw(WarnLevel, msg, keysAndValues)
+}
+
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...any) {
+	s.logw(ErrorLevel, msg, keysAndValues)
+}
+
+func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...any) {
+	s.logw(FatalLevel, msg, keysAndValues)
+	os.Exit(1)
+}
+
+// The ln variants format like fmt.Sprintln: operands are always separated by
+// spaces. The trailing newline is dropped.
+func (s *SugaredLogger) Debugln(args ...any) { s.logln(DebugLevel, args) }
+func (s *SugaredLogger) Infoln(args ...any)  { s.logln(InfoLevel, args) }
+func (s *SugaredLogger) Warnln(args ...any)  { s.logln(WarnLevel, args) }
+func (s *SugaredLogger) Errorln(args ...any) { s.logln(ErrorLevel, args) }
+
+func (s *SugaredLogger) Fatalln(args ...any) {
+	s.logln(FatalLevel, args)
+	os.Exit(1)
+}
+
+func (s *SugaredLogger) logf(level Level, template string, args []any) {
+	if !s.l.enabled(level) {
+		return
+	}
+	msg := template
+	if len(args) > 0 {
+		msg = fmt.Sprintf(template, args...)
+	}
+	s.l.log(level, msg, nil)
+}
+
+func (s *SugaredLogger) logw(level Level, msg string, keysAndValues []any) {
+	if !s.l.enabled(level) {
+		return
+	}
+	var arr [16]Field
+	s.l.log(level, msg, sweeten(arr[:0], keysAndValues))
+}
+
+func (s *SugaredLogger) logln(level Level, args []any) {
+	if !s.l.enabled(level) {
+		return
+	}
+	msg := fmt.Sprintln(args...)
+	s.l.log(level, msg[:len(msg)-1], nil)
+}
+
+// sweeten converts alternating keys and values into fields. A Field in key
+// position is taken as is, a non-string key is formatted, and a key without a
+// value gets "<no-value>".
+func sweeten(dst []Field, kv []any) []Field {
+	for i := 0; i < len(kv); i++ {
+		if f, ok := kv[i].(Field); ok {
+			dst = append(dst, f)
+			continue
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 == len(kv) {
+			dst = append(dst, String(key, "<no-value>"))
+			break
+		}
+		i++
+		dst = append(dst, Any(key, kv[i]))
+	}
+	return dst
+}
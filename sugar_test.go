@@ -0,0 +1,47 @@
+package mach
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingStringer struct{ n *int }
+
+func (c countingStringer) String() string {
+	*c.n++
+	return "counted"
+}
+
+func TestSugaredLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf}).Sugar().With("svc", "api")
+
+	calls := 0
+	log.Debugf("skipped %v", countingStringer{&calls})
+	log.Debugw("skipped", "v", countingStringer{&calls})
+	log.Debugln("skipped", countingStringer{&calls})
+	if calls != 0 || buf.Len() != 0 {
+		t.Fatalf("disabled level formatted %d args, wrote %q", calls, buf.String())
+	}
+
+	log.Infof("user %s has %d items", "bob", 3)
+	log.Warnw("slow", "took", 1500*time.Millisecond, "id", int32(7), Bool("cached", false), "odd")
+	log.Errorln("failed", 42, countingStringer{&calls})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`"msg":"user bob has 3 items","svc":"api"}`,
+		`"msg":"slow","svc":"api","took":1.5,"id":7,"cached":false,"odd":"<no-value>"}`,
+		`"msg":"failed 42 counted","svc":"api"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %q", buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("line %d: %q, want suffix %q", i, lines[i], want[i])
+		}
+	}
+}
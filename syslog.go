@@ -0,0 +1,338 @@
+package mach
+
+import (
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type SyslogFormat uint8
+
+const (
+	RFC5424 SyslogFormat = iota
+	RFC3164
+)
+
+type SyslogFacility uint8
+
+const (
+	FacilityKern SyslogFacility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+)
+
+const (
+	FacilityLocal0 SyslogFacility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+const defaultSDID = "mach@32473"
+
+type SyslogConfig struct {
+	// Network is "udp", "tcp", "unix" or "unixgram". Empty dials the local
+	// syslog socket.
+	Network     string
+	Addr        string
+	TLSConfig   *tls.Config // used when Network is "tcp"
+	DialTimeout time.Duration
+
+	Format   SyslogFormat
+	Facility SyslogFacility
+	AppName  string // defaults to the program name
+	Hostname string // defaults to os.Hostname
+	SDID     string // RFC 5424 SD-ID carrying fields, defaults to "mach@32473"
+}
+
+// SyslogWriter sends each Write as one syslog message, using octet-counting
+// framing on stream connections. A failed connection is re-established on the
+// next write. Pair it with the encoder from Encoder, or use Sink.
+type SyslogWriter struct {
+	cfg SyslogConfig
+	enc *syslogEncoder
+
+	mu     sync.Mutex
+	conn   net.Conn
+	stream bool
+	buf    []byte
+}
+
+func NewSyslogWriter(cfg SyslogConfig) (*SyslogWriter, error) {
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = defaultSDID
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+
+	w := &SyslogWriter{
+		cfg: cfg,
+		enc: &syslogEncoder{
+			format:   cfg.Format,
+			facility: cfg.Facility,
+			hostname: syslogToken(cfg.Hostname, 255),
+			appName:  syslogToken(cfg.AppName, 48),
+			procID:   appendInt64(nil, int64(os.Getpid())),
+			sdID:     cfg.SDID,
+		},
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Encoder returns the encoder producing messages in the configured format.
+func (w *SyslogWriter) Encoder() EntryEncoder { return w.enc }
+
+// Sink returns a Sink writing to w with its encoder.
+func (w *SyslogWriter) Sink(level Level) Sink {
+	return Sink{Output: w, Level: level, Encoder: w.enc}
+}
+
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := p
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		if err = w.send(msg); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *SyslogWriter) send(msg []byte) error {
+	if !w.stream {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	w.buf = appendInt64(w.buf[:0], int64(len(msg)))
+	w.buf = append(w.buf, ' ')
+	w.buf = append(w.buf, msg...)
+	_, err := w.conn.Write(w.buf)
+	return err
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *SyslogWriter) connect() error {
+	d := net.Dialer{Timeout: w.cfg.DialTimeout}
+	if w.cfg.Network == "" {
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			for _, network := range []string{"unixgram", "unix"} {
+				if c, err := d.Dial(network, path); err == nil {
+					w.conn, w.stream = c, network == "unix"
+					return nil
+				}
+			}
+		}
+		return errors.New("mach: no local syslog socket found")
+	}
+
+	var c net.Conn
+	var err error
+	if w.cfg.TLSConfig != nil {
+		c, err = tls.DialWithDialer(&d, w.cfg.Network, w.cfg.Addr, w.cfg.TLSConfig)
+	} else {
+		c, err = d.Dial(w.cfg.Network, w.cfg.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = c
+	switch w.cfg.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.stream = true
+	}
+	return nil
+}
+
+type syslogEncoder struct {
+	format   SyslogFormat
+	facility SyslogFacility
+	hostname string
+	appName  string
+	procID   []byte
+	sdID     string
+}
+
+// syslogSeverity maps a Level onto the RFC 5424 severity scale.
+func syslogSeverity(l Level) int64 {
+	switch {
+	case l >= FatalLevel:
+		return 2
+	case l == ErrorLevel:
+		return 3
+	case l == WarnLevel:
+		return 4
+	case l == InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+func (e *syslogEncoder) AppendContext(dst []byte, fields []Field) []byte {
+	for i := range fields {
+		dst = append(dst, ' ')
+		if e.format == RFC3164 {
+			dst = appendConsoleField(dst, fields[i])
+		} else {
+			dst = appendSDParam(dst, fields[i])
+		}
+	}
+	return dst
+}
+
+func (e *syslogEncoder) AppendEntry(dst []byte, ent Entry, context []byte, fields []Field) []byte {
+	dst = append(dst, '<')
+	dst = appendInt64(dst, int64(e.facility)*8+syslogSeverity(ent.Level))
+	dst = append(dst, '>')
+
+	if e.format == RFC3164 {
+		dst = ent.Time.AppendFormat(dst, time.Stamp)
+		dst = append(dst, ' ')
+		dst = append(dst, e.hostname...)
+		dst = append(dst, ' ')
+		dst = append(dst, e.appName...)
+		dst = append(dst, '[')
+		dst = append(dst, e.procID...)
+		dst = append(dst, "]: "...)
+		dst = appendConsoleString(dst, ent.Message, false)
+		dst = append(dst, context...)
+		for i := range fields {
+			dst = append(dst, ' ')
+			dst = appendConsoleField(dst, fields[i])
+		}
+		return dst
+	}
+
+	dst = append(dst, "1 "...)
+	dst = ent.Time.AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = append(dst, e.hostname...)
+	dst = append(dst, ' ')
+	dst = append(dst, e.appName...)
+	dst = append(dst, ' ')
+	dst = append(dst, e.procID...)
+	dst = append(dst, " - "...)
+	if len(context) == 0 && len(fields) == 0 {
+		dst = append(dst, '-')
+	} else {
+		dst = append(dst, '[')
+		dst = append(dst, e.sdID...)
+		dst = append(dst, context...)
+		for i := range fields {
+			dst = append(dst, ' ')
+			dst = appendSDParam(dst, fields[i])
+		}
+		dst = append(dst, ']')
+	}
+	dst = append(dst, ' ')
+	return append(dst, ent.Message...)
+}
+
+// appendSDParam writes f as an RFC 5424 SD-PARAM: name="value".
+func appendSDParam(dst []byte, f Field) []byte {
+	dst = append(dst, syslogToken(f.Key, 32)...)
+	dst = append(dst, '=', '"')
+	switch f.Type {
+	case StringType, ErrorType:
+		dst = appendSDValue(dst, f.Str)
+	case IntType, Int64Type:
+		dst = appendInt64(dst, f.Ival)
+	case Float64Type:
+		dst = appendFloat64(dst, math.Float64frombits(uint64(f.Ival)))
+	case BoolType:
+		dst = appendBool(dst, f.Ival == 1)
+	case DurationType:
+		dst = appendDuration(dst, time.Duration(f.Ival))
+	case TimeType:
+		dst = time.Unix(0, f.Ival).AppendFormat(dst, time.RFC3339Nano)
+	case BytesType:
+		dst = appendSDValue(dst, string(f.Bval))
+	}
+	return append(dst, '"')
+}
+
+func appendSDValue(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			dst = append(dst, '\\', c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// syslogToken restricts s to printable US-ASCII without spaces, '=', ']' or
+// '"', as required for header fields and SD-NAMEs.
+func syslogToken(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if c := b[j]; c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+					b[j] = '_'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package mach
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readDatagram(t *testing.T, c net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	_ = c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := c.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslogUDP5424(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := NewSyslogWriter(SyslogConfig{
+		Network:  "udp",
+		Addr:     ln.LocalAddr().String(),
+		Facility: FacilityLocal0,
+		AppName:  "api",
+		Hostname: "host1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	log := New(Config{Level: DebugLevel, Sinks: []Sink{w.Sink(DebugLevel)}})
+	log.With(String("svc", "a]b")).Warn("disk low", Int("free", 3))
+
+	got := readDatagram(t, ln)
+	if !strings.HasPrefix(got, "<132>1 ") {
+		t.Fatalf("bad PRI/version: %q", got)
+	}
+	if want := ` host1 api `; !strings.Contains(got, want) {
+		t.Fatalf("missing header %q in %q", want, got)
+	}
+	if want := ` - [mach@32473 svc="a\]b" free="3"] disk low`; !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q, want suffix %q", got, want)
+	}
+}
+
+func TestSyslogUnixgram3164(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	w, err := NewSyslogWriter(SyslogConfig{
+		Network:  "unixgram",
+		Addr:     path,
+		Format:   RFC3164,
+		Facility: FacilityUser,
+		AppName:  "api",
+		Hostname: "host1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	log := New(Config{Sinks: []Sink{w.Sink(InfoLevel)}})
+	log.Error("query failed", String("table", "users"))
+
+	got := readDatagram(t, ln)
+	if !strings.HasPrefix(got, "<11>") {
+		t.Fatalf("bad PRI: %q", got)
+	}
+	if want := " host1 api["; !strings.Contains(got, want) {
+		t.Fatalf("missing %q in %q", want, got)
+	}
+	if want := "]: query failed table=users"; !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q, want suffix %q", got, want)
+	}
+}
+
+func TestSyslogTCPFramingAndReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Drop the connection after one message so the next write has
+			// to reconnect.
+			if msg, err := readOctetCounted(bufio.NewReader(c)); err == nil {
+				lines <- msg
+			}
+			c.Close()
+		}
+	}()
+
+	w, err := NewSyslogWriter(SyslogConfig{Network: "tcp", Addr: ln.Addr().String(), Hostname: "h", AppName: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	log := New(Config{Sinks: []Sink{w.Sink(InfoLevel)}})
+
+	log.Info("first")
+	if got := <-lines; !strings.HasSuffix(got, " - - first") {
+		t.Fatalf("got %q", got)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		log.Info("second")
+		select {
+		case got := <-lines:
+			if !strings.HasSuffix(got, " - - second") {
+				t.Fatalf("got %q", got)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no message after reconnect")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	prefix, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(prefix[:len(prefix)-1])
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+	return string(msg), nil
+}
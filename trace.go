@@ -0,0 +1,170 @@
+package mach
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+)
+
+// TraceContext identifies a span in the W3C Trace Context format. The ID
+// types match those of the OpenTelemetry API, so a SpanContext converts
+// without copying through strings.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+var errInvalidTraceparent = errors.New("mach: invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (TraceContext, error) {
+	var tc TraceContext
+	// version "-" trace-id "-" parent-id "-" trace-flags, and for versions
+	// after 00 possibly more "-"-separated fields.
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tc, errInvalidTraceparent
+	}
+	if len(s) > 55 && (s[0:2] == "00" || s[55] != '-') {
+		return tc, errInvalidTraceparent
+	}
+	var version [1]byte
+	if !decodeLowerHex(version[:], s[0:2]) || version[0] == 0xff {
+		return tc, errInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeLowerHex(tc.TraceID[:], s[3:35]) ||
+		!decodeLowerHex(tc.SpanID[:], s[36:52]) ||
+		!decodeLowerHex(flags[:], s[53:55]) {
+		return tc, errInvalidTraceparent
+	}
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return tc, errInvalidTraceparent
+	}
+	return tc, nil
+}
+
+// decodeLowerHex decodes s into dst, rejecting upper-case digits as the
+// traceparent grammar requires.
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) {
+		return false
+	}
+	for i := range dst {
+		hi, ok1 := lowerHexValue(s[2*i])
+		lo, ok2 := lowerHexValue(s[2*i+1])
+		if !ok1 || !ok2 {
+			return false
+		}
+		dst[i] = hi<<4 | lo
+	}
+	return true
+}
+
+func lowerHexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	}
+	return 0, false
+}
+
+// IsValid reports whether both IDs are non-zero.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 != 0
+}
+
+// String returns tc as a version 00 traceparent value.
+func (tc TraceContext) String() string {
+	b := make([]byte, 0, 55)
+	b = append(b, "00-"...)
+	b = hex.AppendEncode(b, tc.TraceID[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, tc.SpanID[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, []byte{tc.Flags})
+	return string(b)
+}
+
+// TraceFieldNames are the keys used for trace correlation fields. An empty
+// name leaves that field out.
+type TraceFieldNames struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+}
+
+var DefaultTraceFieldNames = TraceFieldNames{
+	TraceID:    "trace_id",
+	SpanID:     "span_id",
+	TraceFlags: "trace_flags",
+}
+
+var traceFieldNames atomic.Pointer[TraceFieldNames]
+
+func init() {
+	names := DefaultTraceFieldNames
+	traceFieldNames.Store(&names)
+}
+
+// SetTraceFieldNames changes the keys used for trace correlation fields, for
+// backends that expect e.g. "dd.trace_id" or "logging.googleapis.com/trace".
+func SetTraceFieldNames(names TraceFieldNames) {
+	traceFieldNames.Store(&names)
+}
+
+type traceKey struct{}
+
+// traceValue keeps the hex forms so logging doesn't re-encode the IDs.
+type traceValue struct {
+	tc      TraceContext
+	traceID string
+	spanID  string
+	flags   string
+}
+
+// ContextWithTrace returns a copy of ctx carrying tc. Entries logged through
+// a *Ctx method with the returned context get trace correlation fields.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, &traceValue{
+		tc:      tc,
+		traceID: hex.EncodeToString(tc.TraceID[:]),
+		spanID:  hex.EncodeToString(tc.SpanID[:]),
+		flags:   hex.EncodeToString([]byte{tc.Flags}),
+	})
+}
+
+// TraceFromContext returns the trace context stored by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if v, ok := ctx.Value(traceKey{}).(*traceValue); ok {
+		return v.tc, true
+	}
+	return TraceContext{}, false
+}
+
+func appendTraceFields(ctx context.Context, dst []Field) []Field {
+	v, ok := ctx.Value(traceKey{}).(*traceValue)
+	if !ok {
+		return dst
+	}
+	names := traceFieldNames.Load()
+	if names.TraceID != "" {
+		dst = append(dst, String(names.TraceID, v.traceID))
+	}
+	if names.SpanID != "" {
+		dst = append(dst, String(names.SpanID, v.spanID))
+	}
+	if names.TraceFlags != "" {
+		dst = append(dst, String(names.TraceFlags, v.flags))
+	}
+	return dst
+}
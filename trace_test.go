@@ -0,0 +1,82 @@
+package mach
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	for _, c := range []struct {
+		name string
+		in   string
+		ok   bool
+	}{
+		{"valid v00", "00-" + traceID + "-" + spanID + "-01", true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true},
+		{"upper-case hex", "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01", false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + spanID + "-01", false},
+		{"zero span id", "00-" + traceID + "-0000000000000000-01", false},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01", false},
+		{"v00 trailing data", "00-" + traceID + "-" + spanID + "-01-extra", false},
+		{"future version", "cc-" + traceID + "-" + spanID + "-01", true},
+		{"future version extra field", "cc-" + traceID + "-" + spanID + "-01-what-the-future-holds", true},
+		{"future version no separator", "cc-" + traceID + "-" + spanID + "-01x", false},
+		{"short", "00-" + traceID + "-" + spanID + "-1", false},
+		{"bad separator", "00_" + traceID + "-" + spanID + "-01", false},
+		{"empty", "", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tc, err := ParseTraceparent(c.in)
+			if (err == nil) != c.ok {
+				t.Fatalf("ParseTraceparent(%q) error = %v", c.in, err)
+			}
+			if !c.ok {
+				return
+			}
+			s := tc.String()
+			if s[3:] != c.in[3:55] {
+				t.Fatalf("round trip %q -> %q", c.in, s)
+			}
+			if tc.Sampled() != (c.in[54] == '1') {
+				t.Fatalf("Sampled() = %v", tc.Sampled())
+			}
+		})
+	}
+}
+
+func TestTraceFieldNames(t *testing.T) {
+	t.Cleanup(func() { SetTraceFieldNames(DefaultTraceFieldNames) })
+
+	tc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithTrace(context.Background(), tc)
+	if got, ok := TraceFromContext(ctx); !ok || got != tc {
+		t.Fatalf("TraceFromContext = %v, %v", got, ok)
+	}
+
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf})
+	for _, c := range []struct {
+		names TraceFieldNames
+		want  string
+	}{
+		{DefaultTraceFieldNames, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"}`},
+		{TraceFieldNames{TraceID: "dd.trace_id", SpanID: "dd.span_id"}, `"msg":"x","dd.trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","dd.span_id":"00f067aa0ba902b7"}`},
+		{TraceFieldNames{}, `"msg":"x"}`},
+	} {
+		SetTraceFieldNames(c.names)
+		buf.Reset()
+		log.InfoCtx(ctx, "x")
+		if got := buf.String(); !strings.HasSuffix(got, c.want+"\n") {
+			t.Errorf("names %+v: got %s", c.names, got)
+		}
+	}
+}
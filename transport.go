@@ -0,0 +1,307 @@
+package mach
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedactedQueryParams are the query parameters whose values Transport
+// hides when TransportConfig.RedactQuery is nil.
+var DefaultRedactedQueryParams = []string{
+	"access_token", "api_key", "apikey", "auth", "client_secret", "code",
+	"key", "password", "secret", "sig", "signature", "token",
+	"x-amz-credential", "x-amz-security-token", "x-amz-signature",
+}
+
+type TransportConfig struct {
+	// RedactQuery lists query parameters, matched case-insensitively, whose
+	// values are logged as "xxxxx". Defaults to DefaultRedactedQueryParams;
+	// "*" redacts every value. URL passwords are always redacted.
+	RedactQuery []string
+	// Level picks the entry's level from the response status. Default:
+	// Error for 5xx, Warn for 4xx, Info otherwise. Transport errors always
+	// log at Error.
+	Level func(status int) Level
+	// MaxRetries is how many times a request is retried after a transport
+	// error or a 429, 502, 503 or 504 response. As with net/http's own
+	// retries, only requests that are safe to repeat are retried: those with
+	// an idempotent method or an Idempotency-Key header, and a body that can
+	// be replayed. Default 0.
+	MaxRetries   int
+	RetryBackoff time.Duration // first retry delay, doubling; default 100ms
+	// MaxBodyBytes, if positive, captures up to that many bytes of the
+	// request and response bodies as request_body and response_body fields.
+	// The response body is captured as the caller reads it, so the entry
+	// is only logged once the caller reaches EOF or closes the body.
+	MaxBodyBytes int
+}
+
+// Transport is an http.RoundTripper that logs one entry per request with
+// method, url, status, bytes (the response Content-Length, when known),
+// retries and latency. Entries go through the *Ctx path with the request's
+// context, so fields stored there, such as a request ID, are included.
+type Transport struct {
+	log    *Logger
+	next   http.RoundTripper
+	cfg    TransportConfig
+	redact map[string]bool
+	all    bool
+}
+
+// NewTransport wraps next, or http.DefaultTransport if nil.
+func NewTransport(l *Logger, next http.RoundTripper, cfg TransportConfig) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.RedactQuery == nil {
+		cfg.RedactQuery = DefaultRedactedQueryParams
+	}
+	if cfg.Level == nil {
+		cfg.Level = statusLevel
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 100 * time.Millisecond
+	}
+	t := &Transport{log: l, next: next, cfg: cfg, redact: make(map[string]bool)}
+	for _, p := range cfg.RedactQuery {
+		if p == "*" {
+			t.all = true
+		}
+		t.redact[strings.ToLower(p)] = true
+	}
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	var reqBody []byte
+	if t.cfg.MaxBodyBytes > 0 {
+		req, reqBody = t.captureRequest(req)
+	}
+
+	resp, retries, err := t.send(req)
+	latency := time.Since(start)
+
+	level := ErrorLevel
+	if err == nil {
+		level = t.cfg.Level(resp.StatusCode)
+	}
+	if !t.log.enabled(level) {
+		return resp, err
+	}
+
+	rl := requestLog{
+		t:       t,
+		req:     req,
+		resp:    resp,
+		err:     err,
+		level:   level,
+		retries: retries,
+		latency: latency,
+		reqBody: reqBody,
+	}
+	if err == nil && t.cfg.MaxBodyBytes > 0 && resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &capturedBody{ReadCloser: resp.Body, limit: t.cfg.MaxBodyBytes, log: &rl}
+		return resp, nil
+	}
+	rl.write(nil)
+	return resp, err
+}
+
+// requestLog is the entry for one request, held back until the response
+// body has been captured.
+type requestLog struct {
+	t       *Transport
+	req     *http.Request
+	resp    *http.Response
+	err     error
+	level   Level
+	retries int
+	latency time.Duration
+	reqBody []byte
+}
+
+func (r *requestLog) write(respBody []byte) {
+	var arr [10]Field
+	fields := append(arr[:0],
+		String("method", r.req.Method),
+		String("url", r.t.redactURL(r.req.URL)),
+	)
+	if r.err != nil {
+		fields = append(fields, Err(r.err))
+	} else {
+		fields = append(fields, Int("status", r.resp.StatusCode))
+		if r.resp.ContentLength >= 0 {
+			fields = append(fields, Int64("bytes", r.resp.ContentLength))
+		}
+	}
+	fields = append(fields, Int("retries", r.retries), Duration("latency", r.latency))
+	if r.reqBody != nil {
+		fields = append(fields, Bytes("request_body", r.reqBody))
+	}
+	if respBody != nil {
+		fields = append(fields, Bytes("response_body", respBody))
+	}
+	r.t.log.logCtx(r.req.Context(), r.level, "http request", fields)
+}
+
+// send performs the request, retrying as configured.
+func (t *Transport) send(req *http.Request) (*http.Response, int, error) {
+	replayable := isReplayable(req)
+	backoff := t.cfg.RetryBackoff
+	for retries := 0; ; retries++ {
+		resp, err := t.next.RoundTrip(req)
+		if retries >= t.cfg.MaxRetries || !replayable || !retryable(resp, err) {
+			return resp, retries, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, retries, req.Context().Err()
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return nil, retries, gerr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// isReplayable reports whether req may be sent again, following the rule
+// net/http applies to its own retries.
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	// The header may be present with a nil value, as net/http allows.
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// captureRequest copies the start of the request body. The request is cloned
+// rather than modified, as RoundTrippers must not change their input.
+func (t *Transport) captureRequest(req *http.Request) (*http.Request, []byte) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return req, nil
+		}
+		b, _ := io.ReadAll(io.LimitReader(body, int64(t.cfg.MaxBodyBytes)))
+		_ = body.Close()
+		return req, b
+	}
+	b, _ := io.ReadAll(io.LimitReader(req.Body, int64(t.cfg.MaxBodyBytes)))
+	clone := req.Clone(req.Context())
+	clone.Body = readCloser{io.MultiReader(bytes.NewReader(b), req.Body), req.Body}
+	return clone, b
+}
+
+// capturedBody copies the start of a response body as the caller reads it,
+// so streaming responses aren't held up, and logs the request when the body
+// reaches EOF or is closed, whichever comes first.
+type capturedBody struct {
+	io.ReadCloser
+	limit int
+
+	mu  sync.Mutex
+	buf []byte
+	log *requestLog // nil once logged
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if b.log != nil && len(b.buf) < b.limit {
+		b.buf = append(b.buf, p[:min(n, b.limit-len(b.buf))]...)
+	}
+	b.mu.Unlock()
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *capturedBody) finish() {
+	b.mu.Lock()
+	rl := b.log
+	b.log = nil
+	b.mu.Unlock()
+	if rl != nil {
+		if b.buf == nil {
+			b.buf = []byte{}
+		}
+		rl.write(b.buf)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// redactURL hides the password and the values of sensitive query parameters.
+func (t *Transport) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	q := u.Query()
+	changed := false
+	for k, vs := range q {
+		if t.all || t.redact[strings.ToLower(k)] {
+			for i := range vs {
+				vs[i] = "xxxxx"
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return u.Redacted()
+	}
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.Redacted()
+}
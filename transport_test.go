@@ -0,0 +1,164 @@
+package mach
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if body, _ := io.ReadAll(r.Body); string(body) != "hello world" {
+			t.Errorf("attempt %d got body %q", calls, body)
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("response body"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log := New(Config{Output: &buf})
+	client := &http.Client{Transport: NewTransport(log, nil, TransportConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		MaxBodyBytes: 5,
+	})}
+
+	ctx := WithFields(context.Background(), String("request_id", "r1"))
+	req, _ := http.NewRequestWithContext(ctx, "PUT", srv.URL+"/v1/items?token=s3cret&page=2", strings.NewReader("hello world"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "response body" {
+		t.Fatalf("caller saw body %q", body)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if entry["level"] != "INFO" || entry["method"] != "PUT" || entry["status"] != 200.0 ||
+		entry["bytes"] != 13.0 || entry["retries"] != 1.0 || entry["request_id"] != "r1" {
+		t.Fatalf("entry %v", entry)
+	}
+	if u := entry["url"].(string); !strings.HasSuffix(u, "/v1/items?page=2&token=xxxxx") {
+		t.Fatalf("url %q", u)
+	}
+	if entry["request_body"] != "hello" || entry["response_body"] != "respo" {
+		t.Fatalf("bodies %v %v", entry["request_body"], entry["response_body"])
+	}
+}
+
+func TestTransportStreamingBody(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	var buf lockedBuffer
+	log := New(Config{Output: &buf})
+	client := &http.Client{Transport: NewTransport(log, nil, TransportConfig{MaxBodyBytes: 64})}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Get waited %v for a body that never ends", d)
+	}
+	event := make([]byte, 13)
+	if _, err := io.ReadFull(resp.Body, event); err != nil || string(event) != "data: first\n\n" {
+		t.Fatalf("read %q, %v", event, err)
+	}
+	if buf.String() != "" {
+		t.Fatalf("logged before the body was done: %s", buf.String())
+	}
+
+	resp.Body.Close()
+	if got := buf.String(); !strings.Contains(got, `"response_body":"data: first\n\n"`) {
+		t.Fatalf("entry after Close: %s", got)
+	}
+	resp.Body.Close()
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Fatalf("%d entries", n)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	var buf bytes.Buffer
+	client := &http.Client{Transport: NewTransport(New(Config{Output: &buf}), nil, TransportConfig{})}
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	if _, err := client.Get(url); err == nil {
+		t.Fatal("expected error")
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if entry["level"] != "ERROR" || entry["error"] == nil || entry["status"] != nil {
+		t.Fatalf("entry %v", entry)
+	}
+}
+
+func TestTransportRetriesOnlyIdempotent(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	client := &http.Client{Transport: NewTransport(New(Config{Output: &buf}), nil, TransportConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})}
+
+	for _, c := range []struct {
+		method, key string
+		calls       int
+	}{
+		{"POST", "", 1},
+		{"PATCH", "", 1},
+		{"POST", "k1", 3},
+		{"DELETE", "", 3},
+	} {
+		calls = 0
+		buf.Reset()
+		req, _ := http.NewRequest(c.method, srv.URL, strings.NewReader("x"))
+		if c.key != "" {
+			req.Header.Set("Idempotency-Key", c.key)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if calls != c.calls || !strings.Contains(buf.String(), `"retries":`+strconv.Itoa(c.calls-1)) {
+			t.Errorf("%s key=%q: %d calls, log %s", c.method, c.key, calls, buf.String())
+		}
+	}
+}